@@ -1,28 +1,38 @@
 package calendar
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/apognu/gocal"
+	"go-slack-ics/i18n"
 	"go-slack-ics/slack"
 	slackUser "go-slack-ics/slack/user"
+	"io"
 	"log"
-	"os"
+	"strings"
 	"time"
 )
 
 type Calendar struct {
+	source *Source
 	events []gocal.Event
 	start  time.Time
 	end    time.Time
 }
 
-func (c *Calendar) GetStartDateForYear(year int) (time.Time, time.Time) {
+// location ist die Zeitzone der Abfuhrtermine.
+func location() *time.Location {
 	location, err := time.LoadLocation("Europe/Berlin")
 	if err != nil {
 		log.Fatal("Fehler beim Laden der Zeitzone:", err)
 	}
-	start := time.Date(year, time.January, 1, 0, 0, 0, 0, location)
-	end := time.Date(year, time.December, 31, 23, 59, 59, 59, location)
+	return location
+}
+
+func (c *Calendar) GetStartDateForYear(year int) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, location())
+	end := time.Date(year, time.December, 31, 23, 59, 59, 59, location())
 
 	return start, end
 }
@@ -39,41 +49,102 @@ func (c *Calendar) GetStartDateForToday() (time.Time, time.Time) {
 }
 
 func (c *Calendar) Init() {
-	f, err := os.Open("./calendar/awb-abfuhrtermine.ics")
-	if err != nil {
-		fmt.Println("Fehler beim Öffnen der Datei:", err)
-		return
-	}
-	defer f.Close()
-
-	cal := gocal.NewParser(f)
-	cal.Start, cal.End = &c.start, &c.end
-	cal.Parse()
-	c.events = cal.Events
+	c.events = DefaultStore.Events(c.source.Name, c.start, c.end)
 }
 
-func (c *Calendar) Notify(user string) string {
+func (c *Calendar) Notify(ctx context.Context, user string) string {
+	now := time.Now()
+	sent := 0
 	for _, e := range c.events {
-		// fmt.Printf("%s on %s\r\n", e.Summary, e.Start)
-		slack.Instance.SendCalenderEvent(e, user)
+		if IsAcknowledged(e) {
+			continue
+		}
+		blocks, err := c.source.Render(c.source.NewTemplateData(e, user, now))
+		if err != nil {
+			log.Printf("Fehler beim Rendern von %s: %v", e.Uid, err)
+			continue
+		}
+		if _, err := slack.Instance.SendBlocks(ctx, user, e.Summary, blocks); err != nil {
+			log.Printf("Fehler beim Senden von %s an %s: %v", e.Uid, user, err)
+			continue
+		}
+		RecordReminder(e, user)
+		sent++
 	}
-	return user + " send notices"
+	return fmt.Sprintf("%s send %d of %d notices", user, sent, len(c.events))
 }
 
-func Run() string {
-	c := Calendar{}
+// Preview rendert die Termine einer Quelle ab dem angegebenen Datum, ohne etwas an Slack zu senden.
+func Preview(w io.Writer, name string, date time.Time) error {
+	source, err := GetSource(name)
+	if err != nil {
+		return err
+	}
 
-	c.start, c.end = c.GetStartDateForDate(time.Now())
+	c := Calendar{source: source}
+	c.start, c.end = c.GetStartDateForDate(date)
 	c.Init()
 
+	if len(c.events) == 0 {
+		locale := i18n.Default()
+		fmt.Fprintln(w, i18n.T(locale, "calendar.no_events", i18n.DateTime(locale, c.start), i18n.DateTime(locale, c.end)))
+		return nil
+	}
+
+	for _, e := range c.events {
+		blocks, err := source.Render(source.NewTemplateData(e, Assignee(date), date))
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(blocks, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "# %s\n%s\n", e.Uid, out)
+	}
+	return nil
+}
+
+// Assignee liefert den Slack-User, der zum angegebenen Zeitpunkt benachrichtigt wird.
+func Assignee(t time.Time) string {
+	if t.Hour() >= 12 {
+		return slackUser.Resolve("Frank")
+	}
+	return slackUser.Resolve("Wolf")
+}
+
+// Run verschickt die Erinnerungen des Ticks für alle Quellen und liefert je Quelle eine
+// Zusammenfassung.
+func Run() string {
+	ctx := context.Background()
 	now := time.Now()
 	slack.Instance = slack.Slack{}
-	var user string
-	if now.Hour() >= 12 {
-		user = slackUser.Users["Frank"]
-	} else if now.Hour() < 12 {
-		user = slackUser.Users["Wolf"]
+	user := Assignee(now)
+
+	result := ""
+	if Delivery() == DeliveryScheduled {
+		// Die Erinnerungen verschickt Slack, der Tick verlängert nur den Planungszeitraum
+		if err := ReconcileSchedule(ctx); err != nil {
+			log.Printf("Fehler beim Abgleich der geplanten Erinnerungen: %v", err)
+		}
+		result = "reminders scheduled via Slack"
+	} else {
+		results := make([]string, 0, len(Sources))
+		for _, source := range Sources {
+			c := Calendar{source: source}
+			c.start, c.end = c.GetStartDateForDate(now)
+			c.Init()
+			results = append(results, source.Name+": "+c.Notify(ctx, user))
+		}
+		result = strings.Join(results, "; ")
+	}
+
+	// Der Mittags-Tick am 31.12. verschickt den Jahresbericht, spätere Ticks nicht mehr
+	if now.Month() == time.December && now.Day() == 31 && now.Hour() >= 12 {
+		if err := SendAnnualReportOnce(ctx, now.Year()); err != nil {
+			log.Printf("Fehler beim Senden des Jahresberichts: %v", err)
+		}
 	}
 
-	return c.Notify(user)
+	return result
 }
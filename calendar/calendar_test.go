@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"context"
+	"go-slack-ics/rotation"
+	"go-slack-ics/slack/slacktest"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/apognu/gocal"
+)
+
+// fake ist der Slack-Fake, an den die Erinnerungen in allen Tests des Pakets gehen.
+var (
+	fake  *slacktest.Server
+	redis *miniredis.Miniredis
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	if redis, err = miniredis.Run(); err != nil {
+		log.Fatal(err)
+	}
+	fake = slacktest.NewServer()
+
+	os.Setenv("REDIS_ADDR", redis.Addr())
+	os.Setenv("SLACK_API_URL", fake.APIURL())
+	os.Setenv("SLACK_TOKEN", "xoxb-test")
+	os.Setenv("SLACK_WOLF", "UWOLF0001")
+	os.Setenv("SLACK_FRANK", "UFRANK001")
+	// Die Tests laufen in calendar/, die Pfade der Quelle sind relativ zum Repo
+	Sources = []*Source{{Name: "awb", Path: "awb-abfuhrtermine.ics", Template: "templates/awb.json.tmpl"}}
+
+	code := m.Run()
+	fake.Close()
+	redis.Close()
+	os.Exit(code)
+}
+
+// calendarFor liefert den Kalender, den der Tick am Vortag des Termins benachrichtigt.
+func calendarFor(t *testing.T, e gocal.Event) Calendar {
+	t.Helper()
+	c := Calendar{source: Sources[0]}
+	c.start, c.end = c.GetStartDateForDate(e.Start.AddDate(0, 0, -1))
+	c.Init()
+	if len(c.events) == 0 {
+		t.Fatalf("keine Termine um %s", e.Start)
+	}
+	return c
+}
+
+// TestNotifyIgnoresRotation prüft, dass beide Personen zu ihren Ticks erinnert werden, egal wer
+// laut Rotation in der Woche Dienst hat.
+func TestNotifyIgnoresRotation(t *testing.T) {
+	redis.FlushAll()
+	fake.Reset()
+	e := bundledEvents(t)["Papier"]
+	c := calendarFor(t, e)
+
+	onDuty := rotation.OnDuty(*e.Start)
+	morning, noon := Assignee(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)), Assignee(time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local))
+	if morning == noon || (onDuty != morning && onDuty != noon) {
+		t.Fatalf("Ticks gehen an %s und %s, Dienst hat %s", morning, noon, onDuty)
+	}
+	for _, user := range []string{morning, noon} {
+		c.Notify(context.Background(), user)
+		if n := len(fake.Calls("chat.postMessage")); n == 0 {
+			t.Fatalf("keine Erinnerung an %s (Dienst hat %s)", user, onDuty)
+		}
+		fake.Reset()
+	}
+}
+
+func TestNotifySkipsAcknowledged(t *testing.T) {
+	redis.FlushAll()
+	fake.Reset()
+	c := calendarFor(t, bundledEvents(t)["Papier"])
+	for _, e := range c.events {
+		RecordAcknowledgement(e, "U1")
+	}
+
+	c.Notify(context.Background(), "U2")
+	if n := len(fake.Calls("chat.postMessage")); n != 0 {
+		t.Fatalf("%d Erinnerungen für bestätigte Termine", n)
+	}
+}
+
+// TestRunReportsEverySource prüft, dass das Ergebnis des Ticks alle Quellen nennt, nicht nur die
+// letzte.
+func TestRunReportsEverySource(t *testing.T) {
+	redis.FlushAll()
+	saved := Sources
+	t.Cleanup(func() { Sources = saved })
+	second := *saved[0]
+	second.Name = "awb-kopie"
+	Sources = append([]*Source{saved[0]}, &second)
+
+	result := Run()
+	for _, source := range Sources {
+		if !strings.Contains(result, source.Name+": ") {
+			t.Errorf("Ergebnis %q ohne %s", result, source.Name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	"strconv"
+	"time"
+)
+
+// CommandName ist der Slash-Befehl für die Abfuhrtermine.
+const CommandName = "/abfuhr"
+
+// RegisterCommands meldet /abfuhr mit den Unterbefehlen stats und erledigt an.
+func RegisterCommands(r *slack.CommandRouter) {
+	r.Register(slack.CommandSpec{
+		Name:        CommandName,
+		Description: "command.abfuhr",
+		Subcommands: []slack.Subcommand{
+			{
+				Name:    "stats",
+				Usage:   "command.abfuhr.stats.usage",
+				Help:    "command.abfuhr.stats",
+				Args:    parseYear,
+				Handler: statsCommand,
+			},
+			{
+				Name:    "erledigt",
+				Aliases: []string{"done"},
+				Help:    "command.abfuhr.erledigt",
+				Handler: doneCommand,
+			},
+		},
+	})
+}
+
+// parseYear liest ein optionales Jahr, ohne Angabe gilt das aktuelle.
+func parseYear(args string, locale string) (interface{}, error) {
+	if args == "" {
+		return time.Now().Year(), nil
+	}
+	year, err := strconv.Atoi(args)
+	if err != nil || year < 2000 || year > 2100 {
+		return nil, errors.New(i18n.T(locale, "command.invalid_year", args))
+	}
+	return year, nil
+}
+
+func statsCommand(_ context.Context, req *slack.CommandRequest) (slack.ResponseMessage, error) {
+	report, err := BuildReport(req.Parsed.(int))
+	if err != nil {
+		return slack.EphemeralResponse(i18n.T(req.Locale, "command.stats_unavailable", err.Error())), nil
+	}
+	return slack.EphemeralResponse(report.Format(req.Locale)), nil
+}
+
+func doneCommand(_ context.Context, req *slack.CommandRequest) (slack.ResponseMessage, error) {
+	e, ok := AcknowledgeNext(req.UserID)
+	if !ok {
+		return slack.EphemeralResponse(i18n.T(req.Locale, "command.nothing_open")), nil
+	}
+	// Die Meldung sieht der ganze Kanal, daher in der Standardsprache
+	return slack.InChannelResponse(i18n.T(i18n.Default(), "command.done", req.UserID, e.Summary)), nil
+}
@@ -0,0 +1,72 @@
+package calendar
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type Link struct {
+	URL   string
+	Label string
+}
+
+// Description ist die für Slack aufbereitete DESCRIPTION eines Termins.
+type Description struct {
+	Text  string
+	Links []Link
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>|]+`)
+
+var icsEscapes = strings.NewReplacer(
+	`\\`, `\`,
+	`\n`, "\n",
+	`\N`, "\n",
+	`\,`, ",",
+	`\;`, ";",
+)
+
+var mrkdwnEscapes = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// UnescapeText löst die Escape-Sequenzen aus RFC 5545 (TEXT) auf.
+func UnescapeText(s string) string {
+	return icsEscapes.Replace(s)
+}
+
+// RenderDescription macht aus dem ICS-Text mrkdwn mit Slack-Links und sammelt die Links ein.
+func RenderDescription(raw string) Description {
+	lines := strings.Split(UnescapeText(raw), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	// Steuerzeichen zuerst maskieren, danach werden die Slack-Links eingesetzt
+	text := mrkdwnEscapes.Replace(strings.TrimSpace(strings.Join(lines, "\n")))
+
+	var links []Link
+	seen := map[string]bool{}
+	text = urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+		// Satzzeichen am Ende gehören nicht mehr zur URL, z. B. "…/restmuelltonne."
+		link := strings.TrimRight(match, ".,;:!?)")
+		rest := match[len(link):]
+
+		target := strings.ReplaceAll(link, "&amp;", "&")
+		label := linkLabel(target)
+		if !seen[target] {
+			seen[target] = true
+			links = append(links, Link{URL: target, Label: label})
+		}
+		return "<" + link + "|" + label + ">" + rest
+	})
+
+	return Description{Text: text, Links: links}
+}
+
+// linkLabel kürzt die URL auf Host und Pfad, z. B. "awbkoeln.de/ersatztonne".
+func linkLabel(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	return strings.TrimPrefix(u.Host, "www.") + strings.TrimRight(u.Path, "/")
+}
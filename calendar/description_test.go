@@ -0,0 +1,115 @@
+package calendar
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+var update = flag.Bool("update", false, "testdata/*.golden neu schreiben")
+
+// goldenNames ordnet die Kategorien des mitgelieferten Kalenders ihren Golden-Dateien zu.
+var goldenNames = map[string]string{
+	"Restmüll":  "restmuell",
+	"Papier":    "papier",
+	"Wertstoff": "wertstoff",
+}
+
+// bundledEvents liest awb-abfuhrtermine.ics und liefert den ersten Termin je Kategorie.
+func bundledEvents(t *testing.T) map[string]gocal.Event {
+	t.Helper()
+	f, err := os.Open("awb-abfuhrtermine.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	start, end := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	cal := gocal.NewParser(f)
+	cal.Start, cal.End = &start, &end
+	if err := cal.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(map[string]gocal.Event)
+	for _, e := range cal.Events {
+		if _, ok := events[category(e)]; !ok {
+			events[category(e)] = e
+		}
+	}
+	return events
+}
+
+func formatDescription(d Description) string {
+	var b strings.Builder
+	b.WriteString(d.Text)
+	b.WriteString("\n--- links\n")
+	for _, link := range d.Links {
+		fmt.Fprintf(&b, "%s | %s\n", link.URL, link.Label)
+	}
+	return b.String()
+}
+
+func TestRenderDescriptionGolden(t *testing.T) {
+	events := bundledEvents(t)
+	if len(events) != len(goldenNames) {
+		t.Fatalf("Kategorien im Kalender: %d, erwartet %d", len(events), len(goldenNames))
+	}
+
+	for cat, name := range goldenNames {
+		t.Run(name, func(t *testing.T) {
+			e, ok := events[cat]
+			if !ok {
+				t.Fatalf("kein Termin der Kategorie %s", cat)
+			}
+			got := formatDescription(RenderDescription(e.Description))
+
+			path := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (mit -update erzeugen)", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s weicht ab:\n--- got\n%s\n--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	tests := []struct {
+		name, raw, text string
+		links           int
+	}{
+		{"escapes", `a\, b\; c\nd`, "a, b; c\nd", 0},
+		{"mrkdwn", "1 < 2 & 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2", 0},
+		{"satzzeichen", "Siehe https://example.org/a.", "Siehe <https://example.org/a|example.org/a>.", 1},
+		{"query", "https://example.org/?a=1&b=2", "<https://example.org/?a=1&amp;b=2|example.org>", 1},
+		{"doppelt", "https://example.org/x https://example.org/x", "<https://example.org/x|example.org/x> <https://example.org/x|example.org/x>", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := RenderDescription(tt.raw)
+			if d.Text != tt.text {
+				t.Errorf("Text = %q, erwartet %q", d.Text, tt.text)
+			}
+			if len(d.Links) != tt.links {
+				t.Errorf("%d Links, erwartet %d", len(d.Links), tt.links)
+			}
+		})
+	}
+	if got := RenderDescription("https://example.org/?a=1&b=2").Links[0].URL; got != "https://example.org/?a=1&b=2" {
+		t.Errorf("Link-URL = %q, & muss unmaskiert sein", got)
+	}
+}
@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"context"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+// ActionAcknowledge ist die action_id des "Erledigt"-Buttons in den Erinnerungen.
+const ActionAcknowledge = "calendar_ack"
+
+// RegisterInteractions meldet die Handler für die Buttons der Erinnerungen an.
+func RegisterInteractions(d *slack.InteractionDispatcher) {
+	d.Action(ActionAcknowledge, acknowledge)
+}
+
+// acknowledge bestätigt den Termin aus dem Button und ersetzt den Button durch einen Vermerk.
+func acknowledge(ctx context.Context, i *slack.Interaction) error {
+	locale := slackUser.Locale(i.User.ID)
+	e, ok := FindEvent(i.Action.Value)
+	if !ok {
+		return i.ReplyEphemeral(ctx, i18n.T(locale, "calendar.event_gone"))
+	}
+	if !IsAcknowledged(e) {
+		RecordAcknowledgement(e, i.User.ID)
+	}
+
+	if i.Message == nil {
+		return nil
+	}
+	blocks := make([]slack.Block, 0, len(i.Message.Blocks))
+	for _, block := range i.Message.Blocks {
+		if block.BlockID == ActionAcknowledge {
+			block = slack.Block{
+				Type:     "context",
+				Elements: slack.Elements{slack.Mrkdwn(i18n.T(locale, "calendar.done_by", i.User.ID))},
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	return i.UpdateOriginal(ctx, slack.Message{Text: e.Summary, Blocks: blocks})
+}
+
+// FindEvent sucht einen Termin für Buttons aus Erinnerungen und dem App-Home, also von einer
+// Woche zurück bis sechs Wochen voraus.
+func FindEvent(uid string) (gocal.Event, bool) {
+	now := time.Now()
+	for _, source := range Sources {
+		for _, e := range DefaultStore.Events(source.Name, now.AddDate(0, 0, -7), now.AddDate(0, 0, 42)) {
+			if e.Uid == uid {
+				return e, true
+			}
+		}
+	}
+	return gocal.Event{}, false
+}
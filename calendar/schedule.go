@@ -0,0 +1,220 @@
+package calendar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	"go-slack-ics/system"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DeliveryTicker verschickt die Erinnerungen zu den Ticks selbst, wie bisher.
+	DeliveryTicker = "ticker"
+	// DeliveryScheduled plant die Erinnerungen mit chat.scheduleMessage bei Slack ein, damit sie
+	// auch ankommen, wenn der Dienst zum Zeitpunkt der Erinnerung nicht läuft.
+	DeliveryScheduled = "scheduled"
+)
+
+const (
+	scheduledKey = "calendar:scheduled"
+	// scheduleHorizon ist der Zeitraum, für den Erinnerungen im Voraus eingeplant werden.
+	scheduleHorizon = 7 * 24 * time.Hour
+	// scheduleLead hält Abstand zu Zeitpunkten, die Slack schon als Vergangenheit ablehnt.
+	scheduleLead = time.Minute
+	// reconcileDelay fasst mehrere Änderungen kurz hintereinander zu einem Abgleich zusammen.
+	reconcileDelay = 5 * time.Second
+)
+
+// Delivery liest CALENDAR_DELIVERY, Standard ist DeliveryTicker.
+func Delivery() string {
+	if os.Getenv("CALENDAR_DELIVERY") == DeliveryScheduled {
+		return DeliveryScheduled
+	}
+	return DeliveryTicker
+}
+
+// ScheduledReminder ist eine bei Slack eingeplante Erinnerung.
+type ScheduledReminder struct {
+	ID       string    `json:"id"`
+	Channel  string    `json:"channel"`
+	PostAt   time.Time `json:"post_at"`
+	EventUID string    `json:"event_uid"`
+	User     string    `json:"user"`
+	// Hash erkennt geänderte Inhalte, z. B. nach einer neuen Beschreibung im Kalender.
+	Hash string `json:"hash"`
+}
+
+func (r ScheduledReminder) key() string {
+	return r.EventUID + "@" + r.PostAt.Format(time.RFC3339) + ":" + r.User
+}
+
+type plannedReminder struct {
+	ScheduledReminder
+	text   string
+	blocks []slack.Block
+}
+
+var (
+	// scheduleMu schützt scheduleOn und den Timer, reconcileMu den Abgleich selbst. Getrennt,
+	// weil der Abgleich den Kalender laden und so scheduleChanged auslösen kann.
+	scheduleMu     sync.Mutex
+	scheduleOn     bool
+	reconcileTimer *time.Timer
+	reconcileMu    sync.Mutex
+)
+
+// EnableScheduledDelivery gleicht die eingeplanten Erinnerungen ab, sobald sich der Kalender, eine
+// Bestätigung oder die Sprache eines Users ändert.
+func EnableScheduledDelivery() {
+	scheduleMu.Lock()
+	scheduleOn = true
+	scheduleMu.Unlock()
+
+	DefaultStore.OnLoad(func(string) { scheduleChanged() })
+	i18n.OnPreferenceChange(func(string) { scheduleChanged() })
+}
+
+// scheduleChanged stößt einen verzögerten Abgleich an, wenn die Zustellung über Slack läuft.
+func scheduleChanged() {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	if !scheduleOn {
+		return
+	}
+	if reconcileTimer != nil {
+		reconcileTimer.Stop()
+	}
+	reconcileTimer = time.AfterFunc(reconcileDelay, func() {
+		if err := ReconcileSchedule(context.Background()); err != nil {
+			log.Printf("Fehler beim Abgleich der geplanten Erinnerungen: %v", err)
+		}
+	})
+}
+
+// tickTimes liefert die Ticks um 00:00 und 12:00 zwischen from und to.
+func tickTimes(from, to time.Time) []time.Time {
+	var ticks []time.Time
+	t := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	for ; !t.After(to); t = t.Add(12 * time.Hour) {
+		if t.After(from) {
+			ticks = append(ticks, t)
+		}
+	}
+	return ticks
+}
+
+// planReminders berechnet, welche Erinnerungen der Ticker in den nächsten Tagen verschicken
+// würde, nach ScheduledReminder.key.
+func planReminders(now time.Time) map[string]plannedReminder {
+	planned := make(map[string]plannedReminder)
+	for _, tick := range tickTimes(now.Add(scheduleLead), now.Add(scheduleHorizon)) {
+		user := Assignee(tick)
+		for _, source := range Sources {
+			c := Calendar{source: source}
+			c.start, c.end = c.GetStartDateForDate(tick)
+			c.Init()
+
+			for _, e := range c.events {
+				if IsAcknowledged(e) {
+					continue
+				}
+				blocks, err := source.Render(source.NewTemplateData(e, user, tick))
+				if err != nil {
+					log.Printf("Fehler beim Rendern von %s: %v", e.Uid, err)
+					continue
+				}
+				content, _ := json.Marshal(blocks)
+				sum := sha256.Sum256(append([]byte(user+e.Summary), content...))
+
+				p := plannedReminder{
+					ScheduledReminder: ScheduledReminder{
+						PostAt:   tick,
+						EventUID: e.Uid,
+						User:     user,
+						Hash:     hex.EncodeToString(sum[:]),
+					},
+					text:   e.Summary,
+					blocks: blocks,
+				}
+				planned[p.key()] = p
+			}
+		}
+	}
+	return planned
+}
+
+// ReconcileSchedule bringt die bei Slack eingeplanten Erinnerungen auf den Stand des Kalenders:
+// überholte werden gelöscht, fehlende eingeplant. Bereits verschickte werden in der Statistik
+// als Erinnerung gezählt.
+func ReconcileSchedule(ctx context.Context) error {
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+
+	redis := system.RedisInstance()
+	var stored []ScheduledReminder
+	if err := redis.Get(scheduledKey, "", &stored); err != nil && !errors.Is(err, system.ErrNil) {
+		return err
+	}
+
+	now := time.Now()
+	planned := planReminders(now)
+	var kept []ScheduledReminder
+	deleted, scheduled := 0, 0
+
+	for _, r := range stored {
+		if !r.PostAt.After(now) {
+			if e, ok := FindEvent(r.EventUID); ok {
+				RecordReminder(e, r.User)
+			}
+			continue
+		}
+		if p, ok := planned[r.key()]; ok && p.Hash == r.Hash {
+			kept = append(kept, r)
+			delete(planned, r.key())
+			continue
+		}
+		if err := slack.Instance.DeleteScheduledMessage(ctx, r.Channel, r.ID); err != nil {
+			var slackErr *slack.SlackError
+			if !errors.As(err, &slackErr) || slackErr.Code != "invalid_scheduled_message_id" {
+				// Beim nächsten Abgleich erneut versuchen
+				log.Printf("Fehler beim Löschen der geplanten Erinnerung %s: %v", r.ID, err)
+				kept = append(kept, r)
+				continue
+			}
+		}
+		deleted++
+	}
+
+	var pending []plannedReminder
+	for _, p := range planned {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].PostAt.Before(pending[j].PostAt)
+	})
+	for _, p := range pending {
+		response, err := slack.Instance.ScheduleMessage(ctx, p.User, p.PostAt, p.text, p.blocks)
+		if err != nil {
+			log.Printf("Fehler beim Einplanen von %s für %s: %v", p.EventUID, p.User, err)
+			continue
+		}
+		p.ID = response.ScheduledMessageID
+		p.Channel = response.Channel
+		kept = append(kept, p.ScheduledReminder)
+		scheduled++
+	}
+
+	if deleted > 0 || scheduled > 0 {
+		log.Printf("Geplante Erinnerungen abgeglichen: %d gelöscht, %d neu, %d insgesamt", deleted, scheduled, len(kept))
+	}
+	return redis.Set(scheduledKey, kept, "")
+}
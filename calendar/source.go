@@ -0,0 +1,164 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+// Source beschreibt eine Kalenderquelle und das Template, mit dem ihre Termine
+// als Block-Kit-JSON für Slack gerendert werden. Ist URL gesetzt, wird der Kalender
+// per HTTP geladen und im Abstand von Refresh aktualisiert, sonst aus Path gelesen.
+type Source struct {
+	Name     string
+	Path     string
+	URL      string
+	Refresh  time.Duration
+	Template string
+
+	tmpl *template.Template
+}
+
+// TemplateData sind die Felder, auf die ein Source-Template zugreifen kann.
+type TemplateData struct {
+	Event       gocal.Event
+	Start       time.Time
+	Description Description
+	Category    string
+	Assignee    string
+	DaysUntil   int
+	// Locale ist die Sprache des Assignee für die Funktionen t und day.
+	Locale string
+}
+
+var Sources = []*Source{
+	{
+		Name:     "awb",
+		Path:     "./calendar/awb-abfuhrtermine.ics",
+		Template: "./calendar/templates/awb.json.tmpl",
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	// json gibt den Wert als JSON-Literal aus, damit Texte sicher im Template landen
+	"json": func(v interface{}) (string, error) {
+		marshal, err := json.Marshal(v)
+		return string(marshal), err
+	},
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	// t übersetzt einen Text aus dem i18n-Catalog, z. B. {{ t .Locale "calendar.done" }}
+	"t": i18n.T,
+	// day formatiert ein Datum in der Schreibweise der Sprache
+	"day": i18n.Date,
+}
+
+func GetSource(name string) (*Source, error) {
+	for _, s := range Sources {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unbekannte Kalenderquelle: %s", name)
+}
+
+func (s *Source) Load() error {
+	content, err := os.ReadFile(s.Template)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", s.Name, err)
+	}
+
+	tmpl, err := template.New(s.Name).Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("template %s: %w", s.Name, err)
+	}
+	s.tmpl = tmpl
+	return nil
+}
+
+func (s *Source) NewTemplateData(e gocal.Event, assignee string, now time.Time) TemplateData {
+	start := *e.Start
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	return TemplateData{
+		Event:       e,
+		Start:       start,
+		Description: RenderDescription(e.Description),
+		Category:    category(e),
+		Assignee:    assignee,
+		DaysUntil:   int(day.Sub(today).Hours() / 24),
+		Locale:      slackUser.Locale(assignee),
+	}
+}
+
+// Render führt das Template aus und prüft, ob das Ergebnis gültige Blocks ergibt.
+func (s *Source) Render(data TemplateData) ([]slack.Block, error) {
+	if s.tmpl == nil {
+		if err := s.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("template %s: %w", s.Name, err)
+	}
+
+	var blocks []slack.Block
+	if err := json.Unmarshal(buf.Bytes(), &blocks); err != nil {
+		return nil, fmt.Errorf("template %s liefert kein gültiges Block-JSON: %w", s.Name, err)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("template %s liefert keine Blocks", s.Name)
+	}
+	if err := slack.ValidateBlocks(blocks, slack.MaxMessageBlocks); err != nil {
+		return nil, fmt.Errorf("template %s: %w", s.Name, err)
+	}
+	return blocks, nil
+}
+
+// ValidateTemplates lädt alle Templates und rendert sie einmal mit einem Beispieltermin.
+func ValidateTemplates() error {
+	start := time.Now()
+	end := start.Add(24 * time.Hour)
+	sample := gocal.Event{
+		Uid:         "sample",
+		Summary:     "Restmüll (grau) AWB Köln",
+		Description: "Weitere Informationen finden Sie unter https://www.awbkoeln.de.",
+		Start:       &start,
+		End:         &end,
+	}
+
+	for _, s := range Sources {
+		if err := s.Load(); err != nil {
+			return err
+		}
+		if _, err := s.Render(s.NewTemplateData(sample, "U00000000", start)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// category nimmt die erste CATEGORIES-Angabe oder den Teil der Summary vor der Farbe,
+// z. B. "Restmüll" aus "Restmüll (grau) AWB Köln".
+func category(e gocal.Event) string {
+	if len(e.Categories) > 0 {
+		return e.Categories[0]
+	}
+	if i := strings.Index(e.Summary, " ("); i > 0 {
+		return e.Summary[:i]
+	}
+	return e.Summary
+}
@@ -0,0 +1,303 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	"go-slack-ics/system"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+const (
+	StatReminder        = "reminder"
+	StatAcknowledgement = "acknowledgement"
+	StatEscalation      = "escalation"
+)
+
+// StatEvent ist ein Eintrag im Protokoll der Erinnerungen eines Jahres.
+type StatEvent struct {
+	Kind      string    `json:"kind"`
+	EventUID  string    `json:"event_uid"`
+	EventDate time.Time `json:"event_date"`
+	Category  string    `json:"category"`
+	User      string    `json:"user"`
+	At        time.Time `json:"at"`
+}
+
+type UserStats struct {
+	Reminders          int     `json:"reminders"`
+	Acknowledgements   int     `json:"acknowledgements"`
+	Escalations        int     `json:"escalations"`
+	AvgAckLatencyHours float64 `json:"avg_ack_latency_hours"`
+
+	latency time.Duration
+}
+
+type MonthStats struct {
+	Month   time.Month `json:"month"`
+	Pickups int        `json:"pickups"`
+	Missed  int        `json:"missed"`
+}
+
+type Report struct {
+	Year   int                   `json:"year"`
+	Users  map[string]*UserStats `json:"users"`
+	Months []MonthStats          `json:"months"`
+}
+
+func statsKey(year int) string {
+	return fmt.Sprintf("calendar:stats:%d", year)
+}
+
+// annualReportKey markiert, dass der Jahresbericht verschickt wurde.
+func annualReportKey(year int) string {
+	return fmt.Sprintf("calendar:report:%d", year)
+}
+
+func record(kind string, e gocal.Event, user string) {
+	entry := StatEvent{
+		Kind:      kind,
+		EventUID:  e.Uid,
+		EventDate: *e.Start,
+		Category:  category(e),
+		User:      user,
+		At:        time.Now(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Fehler beim Speichern der Statistik: %v", err)
+		return
+	}
+	if err := system.RedisInstance().LPush(statsKey(entry.EventDate.Year()), data, ""); err != nil {
+		log.Printf("Fehler beim Speichern der Statistik: %v", err)
+	}
+}
+
+// RecordReminder protokolliert eine Erinnerung. Wurde der Termin schon einmal erinnert und noch
+// nicht bestätigt, zählt die erneute Erinnerung als Eskalation.
+func RecordReminder(e gocal.Event, user string) {
+	entries, err := loadStats(e.Start.Year())
+	if err != nil {
+		log.Printf("Fehler beim Laden der Statistik: %v", err)
+	}
+
+	reminded, acknowledged := false, false
+	for _, entry := range entries {
+		if entry.EventUID != e.Uid {
+			continue
+		}
+		reminded = reminded || entry.Kind == StatReminder
+		acknowledged = acknowledged || entry.Kind == StatAcknowledgement
+	}
+
+	record(StatReminder, e, user)
+	if reminded && !acknowledged {
+		record(StatEscalation, e, user)
+	}
+}
+
+// RecordAcknowledgement protokolliert, dass die Tonne rausgestellt wurde.
+func RecordAcknowledgement(e gocal.Event, user string) {
+	record(StatAcknowledgement, e, user)
+	// Bestätigte Termine brauchen keine eingeplante Erinnerung mehr
+	scheduleChanged()
+}
+
+// IsAcknowledged prüft, ob für den Termin bereits eine Bestätigung vorliegt.
+func IsAcknowledged(e gocal.Event) bool {
+	entries, err := loadStats(e.Start.Year())
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.EventUID == e.Uid && entry.Kind == StatAcknowledgement {
+			return true
+		}
+	}
+	return false
+}
+
+// AcknowledgeNext bestätigt den nächsten offenen Termin ab heute.
+func AcknowledgeNext(user string) (gocal.Event, bool) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for _, source := range Sources {
+		for _, e := range DefaultStore.Events(source.Name, today, today.Add(3*24*time.Hour)) {
+			if !IsAcknowledged(e) {
+				RecordAcknowledgement(e, user)
+				return e, true
+			}
+		}
+	}
+	return gocal.Event{}, false
+}
+
+func loadStats(year int) ([]StatEvent, error) {
+	values, err := system.RedisInstance().LRange(statsKey(year), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StatEvent, 0, len(values))
+	// LPush speichert das neueste Element vorne, daher rückwärts lesen
+	for i := len(values) - 1; i >= 0; i-- {
+		var entry StatEvent
+		if err := json.Unmarshal([]byte(values[i]), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// BuildReport wertet das Protokoll eines Jahres aus. Die Abholungen kommen aus den Kalendern,
+// so zählen auch Termine ohne Erinnerung oder Bestätigung als verpasst.
+func BuildReport(year int) (Report, error) {
+	report := Report{Year: year, Users: map[string]*UserStats{}}
+	for m := time.January; m <= time.December; m++ {
+		report.Months = append(report.Months, MonthStats{Month: m})
+	}
+
+	entries, err := loadStats(year)
+	if err != nil {
+		return report, err
+	}
+
+	userStats := func(user string) *UserStats {
+		if report.Users[user] == nil {
+			report.Users[user] = &UserStats{}
+		}
+		return report.Users[user]
+	}
+
+	firstReminder := map[string]time.Time{}
+	acknowledged := map[string]bool{}
+	eventDates := map[string]time.Time{}
+	for _, entry := range entries {
+		stats := userStats(entry.User)
+		switch entry.Kind {
+		case StatReminder:
+			stats.Reminders++
+			if _, ok := firstReminder[entry.EventUID]; !ok {
+				firstReminder[entry.EventUID] = entry.At
+			}
+		case StatEscalation:
+			stats.Escalations++
+		case StatAcknowledgement:
+			if acknowledged[entry.EventUID] {
+				continue
+			}
+			acknowledged[entry.EventUID] = true
+			stats.Acknowledgements++
+			if reminded, ok := firstReminder[entry.EventUID]; ok {
+				stats.latency += entry.At.Sub(reminded)
+			}
+		}
+		eventDates[entry.EventUID] = entry.EventDate
+	}
+
+	var c Calendar
+	start, end := c.GetStartDateForYear(year)
+	for _, source := range Sources {
+		for _, e := range DefaultStore.Events(source.Name, start, end) {
+			if _, ok := eventDates[e.Uid]; !ok {
+				eventDates[e.Uid] = *e.Start
+			}
+		}
+	}
+
+	for _, stats := range report.Users {
+		if stats.Acknowledgements > 0 {
+			stats.AvgAckLatencyHours = stats.latency.Hours() / float64(stats.Acknowledgements)
+		}
+	}
+
+	today := startOfDay(time.Now())
+	for uid, date := range eventDates {
+		month := &report.Months[date.Month()-1]
+		month.Pickups++
+		if !acknowledged[uid] && date.Before(today) {
+			month.Missed++
+		}
+	}
+
+	return report, nil
+}
+
+// startOfDay liefert Mitternacht des Tages in Berlin. time.Truncate rechnete in UTC, dann
+// begänne der Tag erst um 01:00 bzw. 02:00.
+func startOfDay(t time.Time) time.Time {
+	t = t.In(location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Format bereitet den Bericht als mrkdwn für Slack in der angegebenen Sprache auf.
+func (r Report) Format(locale string) string {
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "stats.title", r.Year) + "\n")
+
+	users := make([]string, 0, len(r.Users))
+	for user := range r.Users {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		stats := r.Users[user]
+		b.WriteString(i18n.T(locale, "stats.user",
+			user, stats.Reminders, stats.Acknowledgements, stats.Escalations, stats.AvgAckLatencyHours) + "\n")
+	}
+
+	missed := 0
+	for _, month := range r.Months {
+		if month.Pickups == 0 {
+			continue
+		}
+		missed += month.Missed
+		b.WriteString(i18n.T(locale, "stats.month", i18n.Month(locale, month.Month), month.Pickups, month.Missed) + "\n")
+	}
+	b.WriteString(i18n.T(locale, "stats.missed_total", missed))
+
+	return b.String()
+}
+
+// SendAnnualReport schickt den Jahresbericht in den Haushaltskanal aus SLACK_HOUSEHOLD_CHANNEL.
+func SendAnnualReport(ctx context.Context, year int) error {
+	channel := os.Getenv("SLACK_HOUSEHOLD_CHANNEL")
+	if channel == "" {
+		return fmt.Errorf("SLACK_HOUSEHOLD_CHANNEL ist nicht gesetzt")
+	}
+
+	report, err := BuildReport(year)
+	if err != nil {
+		return err
+	}
+
+	_, err = slack.Instance.SendMessage(ctx, channel, "", slack.GetSimpleMessage("", channel, report.Format(i18n.Default())))
+	return err
+}
+
+// SendAnnualReportOnce schickt den Jahresbericht nur beim ersten Aufruf für das Jahr. Die
+// Markierung liegt in Redis und gilt damit auch über Neustarts hinweg; schlägt der Versand
+// fehl, wird sie entfernt und der nächste Tick versucht es erneut.
+func SendAnnualReportOnce(ctx context.Context, year int) error {
+	redis := system.RedisInstance()
+	first, err := redis.SetNX(annualReportKey(year), time.Now().Unix(), 0)
+	if err != nil || !first {
+		return err
+	}
+	if err := SendAnnualReport(ctx, year); err != nil {
+		redis.Del(annualReportKey(year))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStartOfDay(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"kurz nach mitternacht in berlin", time.Date(2025, 3, 1, 23, 30, 0, 0, time.UTC), "2025-03-02"},
+		{"sommerzeit", time.Date(2025, 7, 1, 22, 30, 0, 0, time.UTC), "2025-07-02"},
+		{"nachmittag", time.Date(2025, 7, 1, 15, 0, 0, 0, time.UTC), "2025-07-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := startOfDay(tt.now)
+			if got.Format("2006-01-02") != tt.want || got.Hour() != 0 || got.Location().String() != "Europe/Berlin" {
+				t.Fatalf("%s ergibt %s, erwartet %s 00:00 in Berlin", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildReportCountsUnrecordedPickups prüft, dass vergangene Abholungen auch ohne Eintrag im
+// Protokoll als verpasst zählen.
+func TestBuildReportCountsUnrecordedPickups(t *testing.T) {
+	redis.FlushAll()
+	var c Calendar
+	start, end := c.GetStartDateForYear(2025)
+	events := DefaultStore.Events(Sources[0].Name, start, end)
+	if len(events) < 2 {
+		t.Fatalf("%d Termine 2025", len(events))
+	}
+	RecordReminder(events[0], "U1")
+	RecordAcknowledgement(events[0], "U1")
+	RecordReminder(events[1], "U2")
+
+	report, err := BuildReport(2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pickups, missed := 0, 0
+	for _, month := range report.Months {
+		pickups += month.Pickups
+		missed += month.Missed
+	}
+	// Alle Termine 2025 liegen in der Vergangenheit, nur der erste ist bestätigt
+	if pickups != len(events) || missed != len(events)-1 {
+		t.Fatalf("%d Abholungen, %d verpasst, erwartet %d und %d", pickups, missed, len(events), len(events)-1)
+	}
+	if report.Users["U1"].Acknowledgements != 1 || report.Users["U2"].Reminders != 1 {
+		t.Fatalf("User-Statistik %+v %+v", report.Users["U1"], report.Users["U2"])
+	}
+}
+
+func TestSendAnnualReportOnce(t *testing.T) {
+	redis.FlushAll()
+	fake.Reset()
+
+	// Ohne Kanal schlägt der Versand fehl, der nächste Tick darf es erneut versuchen
+	t.Setenv("SLACK_HOUSEHOLD_CHANNEL", "")
+	if err := SendAnnualReportOnce(context.Background(), 2025); err == nil {
+		t.Fatal("Versand ohne SLACK_HOUSEHOLD_CHANNEL ohne Fehler")
+	}
+	if redis.Exists(annualReportKey(2025)) {
+		t.Fatal("fehlgeschlagener Versand ist als verschickt markiert")
+	}
+
+	t.Setenv("SLACK_HOUSEHOLD_CHANNEL", "CHAUSHALT")
+	for tick := 0; tick < 3; tick++ {
+		if err := SendAnnualReportOnce(context.Background(), 2025); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(fake.Calls("chat.postMessage")); n != 1 {
+		t.Fatalf("%d Jahresberichte, erwartet 1", n)
+	}
+}
@@ -0,0 +1,224 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apognu/gocal"
+	"github.com/fsnotify/fsnotify"
+)
+
+const dayKey = "2006-01-02"
+
+// Store hält die geparsten Termine aller Quellen im Speicher, nach Tag indiziert.
+type Store struct {
+	mu       sync.RWMutex
+	days     map[string]map[string][]gocal.Event
+	loadedAt map[string]time.Time
+	onLoad   []func(name string)
+}
+
+func NewStore() *Store {
+	return &Store{
+		days:     make(map[string]map[string][]gocal.Event),
+		loadedAt: make(map[string]time.Time),
+	}
+}
+
+var DefaultStore = NewStore()
+
+// Load liest eine Quelle neu ein und ersetzt deren Index.
+func (s *Store) Load(source *Source) error {
+	r, err := source.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Alle vergangenen Termine behalten (Vorschau, Statistik), Wiederholungen bis zwei Jahre voraus
+	now := time.Now()
+	start, end := time.Time{}, now.AddDate(2, 0, 0)
+
+	cal := gocal.NewParser(r)
+	cal.Start, cal.End = &start, &end
+	if err := cal.Parse(); err != nil {
+		return fmt.Errorf("kalender %s: %w", source.Name, err)
+	}
+
+	days := make(map[string][]gocal.Event)
+	for _, e := range cal.Events {
+		if e.Start == nil || e.End == nil {
+			continue
+		}
+		for d := *e.Start; !d.After(*e.End); d = d.Add(24 * time.Hour) {
+			days[d.Format(dayKey)] = append(days[d.Format(dayKey)], e)
+		}
+	}
+
+	s.mu.Lock()
+	s.days[source.Name] = days
+	s.loadedAt[source.Name] = now
+	listeners := s.onLoad
+	s.mu.Unlock()
+
+	log.Printf("Kalender %s geladen: %d Termine", source.Name, len(cal.Events))
+	for _, fn := range listeners {
+		fn(source.Name)
+	}
+	return nil
+}
+
+// OnLoad registriert eine Funktion, die nach jedem Laden einer Quelle aufgerufen wird.
+func (s *Store) OnLoad(fn func(name string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onLoad = append(s.onLoad, fn)
+}
+
+// Reload lädt alle Quellen neu.
+func (s *Store) Reload() error {
+	for _, source := range Sources {
+		if err := s.Load(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Events liefert die Termine einer Quelle, die sich mit [from, to) überschneiden.
+func (s *Store) Events(name string, from, to time.Time) []gocal.Event {
+	s.mu.RLock()
+	days, ok := s.days[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		source, err := GetSource(name)
+		if err != nil {
+			return nil
+		}
+		if err := s.Load(source); err != nil {
+			log.Printf("Fehler beim Laden von %s: %v", name, err)
+			return nil
+		}
+		s.mu.RLock()
+		days = s.days[name]
+		s.mu.RUnlock()
+	}
+
+	var events []gocal.Event
+	seen := map[string]bool{}
+	for d := from.UTC().Add(-24 * time.Hour); d.Before(to.Add(24 * time.Hour)); d = d.Add(24 * time.Hour) {
+		for _, e := range days[d.Format(dayKey)] {
+			key := e.Uid + e.Start.String()
+			if seen[key] || !e.Start.Before(to) || !e.End.After(from) {
+				continue
+			}
+			seen[key] = true
+			events = append(events, e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Start.Before(*events[j].Start)
+	})
+	return events
+}
+
+func (s *Store) LoadedAt(name string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt[name]
+}
+
+// Watch lädt Dateiquellen bei Änderungen neu. Zusätzlich wird jede Quelle im Intervall
+// aktualisiert, damit Remote-Kalender und das Ladefenster aktuell bleiben.
+func (s *Store) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// Verzeichnisse beobachten, da Editoren die Datei beim Speichern oft ersetzen
+	files := map[string]*Source{}
+	for _, source := range Sources {
+		go s.refresh(source)
+		if source.URL != "" {
+			continue
+		}
+		path, err := filepath.Abs(source.Path)
+		if err != nil {
+			return err
+		}
+		files[path] = source
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				source := files[event.Name]
+				if source == nil || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+					continue
+				}
+				if err := s.Load(source); err != nil {
+					log.Printf("Fehler beim Neuladen von %s: %v", source.Name, err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Fehler beim Beobachten der Kalender: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Store) refresh(source *Source) {
+	interval := source.Refresh
+	if interval == 0 && source.URL != "" {
+		interval = 6 * time.Hour
+	} else if interval == 0 {
+		interval = 24 * time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.Load(source); err != nil {
+			log.Printf("Fehler beim Aktualisieren von %s: %v", source.Name, err)
+		}
+	}
+}
+
+// open liefert den Inhalt der Quelle, entweder aus der Datei oder per HTTP.
+func (source *Source) open() (io.ReadCloser, error) {
+	if source.URL == "" {
+		return os.Open(source.Path)
+	}
+
+	resp, err := http.Get(source.URL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("kalender %s: %s", source.Name, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -2,6 +2,7 @@ package clipdrop
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"go-slack-ics/slack"
 	"hash/fnv"
@@ -32,7 +33,7 @@ func (tti *TextToImage) hash(s string) string {
 	return strconv.FormatUint(h.Sum64(), 10)
 }
 
-func (tti *TextToImage) Prompt(event slack.Command) (*TtiResponse, error) {
+func (tti *TextToImage) Prompt(ctx context.Context, event slack.Command) (*TtiResponse, error) {
 	// message := slack.GetSimpleMessage(event.UserID, event.ChannelID, fmt.Sprintf("imagine .o0(%s); please wait...", event.Text))
 	// initMessageResponse := slack.Instance.SendMessage(event.ChannelID, event.UserID, message)
 	if event.Text == "" {
@@ -51,18 +52,18 @@ func (tti *TextToImage) Prompt(event slack.Command) (*TtiResponse, error) {
 
 	// Füge das Feld 'prompt' zum Formular hinzu
 	if err := multipartWriter.WriteField("prompt", event.Text); err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Wichtig: Schließe den multipart writer, um das Ende des Formulars zu signalisieren
 	if err := multipartWriter.Close(); err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Erstelle die Anfrage
-	req, err := http.NewRequest("POST", url, &requestBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &requestBody)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Füge den API-Key und den Content-Type Header hinzu
@@ -73,10 +74,16 @@ func (tti *TextToImage) Prompt(event slack.Command) (*TtiResponse, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("clipdrop: %w", err)
 	}
 	defer resp.Body.Close()
 	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("clipdrop: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("clipdrop: %s: %s", resp.Status, bodyBytes)
+	}
 
 	currentTime := time.Now()
 	filename := tti.hash(event.Text+"-"+currentTime.Format("2006-01-02 15:04:05")) + ".png"
@@ -87,10 +94,13 @@ func (tti *TextToImage) Prompt(event slack.Command) (*TtiResponse, error) {
 	fmt.Println("TextToImage: ", filename)
 	fmt.Println("TextToImage: ", event.Text)
 	fmt.Println("ChannelID: ", event.ChannelID)
-	err = slack.Instance.SendImageToSlack(bodyBytes, filename, event.Text, event.ChannelID)
+	uploaded, err := slack.Instance.SendImageToSlack(ctx, bodyBytes, filename, event.Text, event.ChannelID)
 	if err != nil {
 		return nil, err
 	}
+	if len(uploaded.Files) > 0 {
+		result.ImageUrl = uploaded.Files[0].Permalink
+	}
 	// slack.Instance.ChangeMessage(initMessageResponse.Ts, event.ChannelID, event.UserID, slackMessage)
 
 	return &result, nil
@@ -0,0 +1,86 @@
+package gpt
+
+import (
+	"go-slack-ics/slack"
+	"os"
+	"strings"
+)
+
+const (
+	// ModeAlways beantwortet jede Nachricht, wie bisher.
+	ModeAlways = "always"
+	// ModeMention antwortet nur, wenn der Bot erwähnt wird.
+	ModeMention = "mention"
+	// ModeDM antwortet in Direktnachrichten immer, in Kanälen nur bei Erwähnung.
+	ModeDM = "dm"
+)
+
+// Filter entscheidet, ob ein Event an GPT weitergegeben wird.
+type Filter struct {
+	BotUserID string
+	Channels  map[string]bool
+	Mode      string
+}
+
+// NewFilter liest GPT_MODE, GPT_CHANNELS (kommagetrennte Kanal-IDs) und SLACK_BOT_USER_ID.
+func NewFilter() Filter {
+	filter := Filter{
+		BotUserID: os.Getenv("SLACK_BOT_USER_ID"),
+		Channels:  make(map[string]bool),
+		Mode:      os.Getenv("GPT_MODE"),
+	}
+	if filter.Mode == "" {
+		filter.Mode = ModeAlways
+	}
+
+	for _, channel := range strings.Split(os.Getenv("GPT_CHANNELS"), ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			filter.Channels[channel] = true
+		}
+	}
+	return filter
+}
+
+// ForPayload übernimmt die User-ID des Bots aus den Authorizations. Sie geht der Konfiguration
+// vor, weil der Bot in jedem Workspace eine eigene User-ID hat.
+func (f Filter) ForPayload(payload slack.Payload) Filter {
+	for _, authorization := range payload.Authorizations {
+		if authorization.IsBot {
+			f.BotUserID = authorization.UserID
+			break
+		}
+	}
+	return f
+}
+
+func (f Filter) Accept(event slack.Event) bool {
+	// Bearbeitungen (auch die eigenen chat.update beim Streaming), Löschungen und Bot-Nachrichten
+	if event.Subtype != "" && event.Subtype != "thread_broadcast" && event.Subtype != "file_share" {
+		return false
+	}
+	if event.BotID != "" || event.BotProfile != nil {
+		return false
+	}
+	if f.BotUserID != "" && event.User == f.BotUserID {
+		return false
+	}
+
+	isDM := event.ChannelType == "im"
+	if !isDM && len(f.Channels) > 0 && !f.Channels[event.Channel] {
+		return false
+	}
+
+	mentioned := f.BotUserID != "" && strings.Contains(event.Text, "<@"+f.BotUserID+">")
+	// In Threads, in denen der Bot schon antwortet, geht es ohne erneute Erwähnung weiter
+	if event.ThreadTs != "" && HasConversation(event.Channel, event.ThreadTs) {
+		mentioned = true
+	}
+	switch f.Mode {
+	case ModeMention:
+		return mentioned
+	case ModeDM:
+		return isDM || mentioned
+	default:
+		return true
+	}
+}
@@ -1,22 +1,29 @@
 package gpt
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"go-slack-ics/i18n"
 	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
 	"go-slack-ics/system"
 	"io"
 	"log"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
+const updateInterval = time.Second
+
 type Chat struct {
 	resty        *resty.Client
 	cancelChanel chan system.EventMessage
 	eventManager *system.EventManager
+	replies      ReplySettings
 	cancel       bool
 }
 
@@ -54,11 +61,17 @@ type Choice struct {
 	FinishReason string `json:"finish_reason"`
 }
 
-func (c *Chat) SendAsync(message slack.Event) chan slack.Response {
+// SendAsync beantwortet die Nachricht im Hintergrund. ctx bestimmt mit slack.WithTeam den
+// Workspace der Antwort.
+func (c *Chat) SendAsync(ctx context.Context, message slack.Event) chan slack.Response {
 	c.cancelChanel = c.eventManager.RegisterChannel(message.Channel)
 	rChan := make(chan slack.Response)
 	go c.CancelObserver()
-	go c.Send(message, rChan)
+	go func() {
+		if _, err := c.Send(ctx, message, rChan); err != nil {
+			log.Printf("GPT-Antwort in %s fehlgeschlagen: %v", message.Channel, err)
+		}
+	}()
 	return rChan
 }
 
@@ -74,14 +87,9 @@ func (c *Chat) CancelObserver() {
 }
 
 func (c *Chat) AddMessageToConversation(conversationId string, message Message) {
-	if conversationStorage == nil {
-		conversationStorage = make(map[string][]Message)
+	if err := Conversations().Append(conversationId, message); err != nil {
+		log.Printf("Fehler beim Speichern des Verlaufs %s: %v", conversationId, err)
 	}
-
-	if len(conversationStorage[conversationId]) > 20 {
-		conversationStorage[conversationId] = conversationStorage[conversationId][len(conversationStorage[conversationId])-20:]
-	}
-	conversationStorage[conversationId] = append(conversationStorage[conversationId], message)
 }
 
 func (c *Chat) GetMessageInConversation(conversationId string) []Message {
@@ -92,31 +100,48 @@ func (c *Chat) GetMessageInConversation(conversationId string) []Message {
 		},
 	}
 
-	return append(defaultSystemMessages, conversationStorage[conversationId]...)
+	messages, err := Conversations().Messages(conversationId)
+	if err != nil {
+		log.Printf("Fehler beim Laden des Verlaufs %s: %v", conversationId, err)
+	}
+	return append(defaultSystemMessages, messages...)
 }
 
-func (c *Chat) Send(event slack.Event, responseChan chan slack.Response) slack.Response {
+func (c *Chat) Send(ctx context.Context, event slack.Event, responseChan chan slack.Response) (slack.Response, error) {
+	threadTs := c.replies.ThreadTs(event)
+	conversationId := ConversationKey(event.Channel, threadTs)
+	locale := slackUser.Locale(event.User)
+
 	if strings.Contains(event.Text, "/text-to-image") {
-		responseCreateImage := slack.Instance.SendMessage(event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, "... creating Image ..."))
-		responseChan <- responseCreateImage
-		return responseCreateImage
+		responseCreateImage, err := slack.Instance.SendMessage(ctx, event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, i18n.T(locale, "gpt.creating_image")).InThread(threadTs))
+		go func() {
+			responseChan <- responseCreateImage
+		}()
+		return responseCreateImage, err
 	}
 
-	response := slack.Instance.SendMessage(event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, "... thinking ..."))
+	recordUsage(event.User, time.Now())
+	response, err := slack.Instance.SendMessage(ctx, event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, i18n.T(locale, "gpt.thinking")).InThread(threadTs))
+	if err != nil {
+		return response, err
+	}
 	go func() {
 		responseChan <- response
 	}()
 
 	event.Timestamp = response.Ts
+	reply := &answer{event: event, threadTs: threadTs, ts: []string{response.Ts}}
 
-	c.AddMessageToConversation(event.Channel, Message{
+	c.AddMessageToConversation(conversationId, Message{
 		Role:    "user",
-		Content: event.UserName + ": " + event.Text,
+		Content: speaker(event) + ": " + event.Text,
 	})
 
-	messages := c.GetMessageInConversation(event.Channel)
+	model := os.Getenv("GPT_MODEL")
+	messages, dropped, truncated := FitContext(model, ContextBudget(model), c.GetMessageInConversation(conversationId))
+	reply.notice = contextNotice(locale, dropped, truncated)
 	data := Data{
-		Model:       os.Getenv("GPT_MODEL"),
+		Model:       model,
 		Messages:    messages,
 		Temperature: 0,
 		Stream:      true, // again, we set stream=True
@@ -124,29 +149,35 @@ func (c *Chat) Send(event slack.Event, responseChan chan slack.Response) slack.R
 
 	dataStr, err := json.Marshal(data)
 	if err != nil {
-		log.Fatalf("Error occurred while marshalling data: %v", err)
+		return c.fail(ctx, event, fmt.Errorf("Error occurred while marshalling data: %w", err))
 	}
 
 	resp, err := c.resty.R().
+		SetContext(ctx).
 		SetDoNotParseResponse(true).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Authorization", "Bearer "+os.Getenv("OPEN_AI_TOKEN")).
 		SetBody(dataStr).
 		Post("https://api.openai.com/v1/chat/completions")
 	if err != nil {
-		log.Fatalf("Error occurred while sending request: %v", err)
+		return c.fail(ctx, event, fmt.Errorf("Error occurred while sending request: %w", err))
 	}
 
 	// Der RawBody() Methode gibt einen io.ReadCloser zurück, der dann verwendet werden kann, um die Daten zu lesen.
 	body := resp.RawBody()
 	defer body.Close() // Stellen Sie sicher, dass Sie den Body schließen, wenn Sie fertig sind.
 
+	if resp.StatusCode() != 200 {
+		return c.fail(ctx, event, fmt.Errorf("OpenAI antwortet mit %s", resp.Status()))
+	}
+
 	gptResponseChunk := ""
 	gptResponseString := ""
 	buf := make([]byte, 1024) // Puffer zum Halten der gelesenen Daten.
 	var dataObj Data
 	// Vor der Schleife definieren
 	var lastContent string
+	var lastUpdate time.Time
 
 	for {
 		n, err := body.Read(buf)
@@ -158,7 +189,7 @@ func (c *Chat) Send(event slack.Event, responseChan chan slack.Response) slack.R
 			break // Beenden Sie die Schleife, wenn das Ende der Daten erreicht ist.
 		}
 		if err != nil {
-			log.Fatalf("Error reading from body: %v", err)
+			return c.fail(ctx, event, fmt.Errorf("Error reading from body: %w", err))
 		}
 
 		// Verarbeiten Sie die gelesenen Daten...
@@ -169,13 +200,18 @@ func (c *Chat) Send(event slack.Event, responseChan chan slack.Response) slack.R
 			err := json.Unmarshal([]byte(jsonStr), &dataObj)
 			if err != nil {
 				log.Printf("Error parsing JSON: %v", err)
-			} else {
+			} else if len(dataObj.Choices) > 0 {
 				deltaContent := dataObj.Choices[0].Delta.Content
-				// Nur den neuen Teil an Slack senden
+				// Nur den neuen Teil an Slack senden, höchstens einmal pro Sekunde wegen des Rate-Limits von chat.update
 				if deltaContent != lastContent {
-					response = slack.Instance.ChangeMessage(event.Timestamp, event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, gptResponseString+deltaContent))
 					lastContent = deltaContent
 					gptResponseString = gptResponseString + deltaContent
+					if time.Since(lastUpdate) >= updateInterval {
+						if err := reply.update(ctx, gptResponseString); err != nil {
+							log.Printf("Fehler beim Aktualisieren der Antwort: %v", err)
+						}
+						lastUpdate = time.Now()
+					}
 				}
 				fmt.Println(match, "found at index", i)
 				log.Printf("Read %d bytes: %s", n, buf[:n])
@@ -185,12 +221,97 @@ func (c *Chat) Send(event slack.Event, responseChan chan slack.Response) slack.R
 		}
 	}
 
-	c.AddMessageToConversation(event.Channel, Message{
+	err = reply.update(ctx, gptResponseString)
+	if reply.last.Ts != "" {
+		response = reply.last
+	}
+
+	c.AddMessageToConversation(conversationId, Message{
 		Role:    "assistant",
 		Content: gptResponseString,
 	})
 
-	return response
+	return response, err
+}
+
+// answer hält die Nachrichten einer Antwort. Die erste ist die "thinking"-Nachricht, wird die
+// Antwort für eine Nachricht zu lang, kommen weitere im selben Thread dazu.
+type answer struct {
+	event    slack.Event
+	threadTs string
+	ts       []string
+	sent     []string
+	last     slack.Response
+	// notice steht über der Antwort, z. B. wenn ältere Nachrichten nicht mehr in den Kontext passen
+	notice string
+}
+
+// update bringt die Nachrichten auf den Stand von markdown. Nachrichten, deren Blöcke sich
+// nicht geändert haben, werden nicht erneut gesendet.
+func (a *answer) update(ctx context.Context, markdown string) error {
+	if a.notice != "" {
+		markdown = a.notice + "\n\n" + markdown
+	}
+	for i, message := range slack.MarkdownMessages(a.event.User, a.event.Channel, markdown) {
+		blocks, err := json.Marshal(message.Blocks)
+		if err != nil {
+			return err
+		}
+		if i < len(a.sent) && a.sent[i] == string(blocks) {
+			continue
+		}
+
+		var response slack.Response
+		if i < len(a.ts) {
+			response, err = slack.Instance.ChangeMessage(ctx, a.ts[i], a.event.Channel, a.event.User, message)
+		} else {
+			response, err = slack.Instance.SendMessage(ctx, a.event.Channel, a.event.User, message.InThread(a.threadTs))
+			if err == nil {
+				a.ts = append(a.ts, response.Ts)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if i < len(a.sent) {
+			a.sent[i] = string(blocks)
+		} else {
+			a.sent = append(a.sent, string(blocks))
+		}
+		a.last = response
+	}
+	return nil
+}
+
+// contextNotice erklärt dem User, dass GPT nicht den ganzen Verlauf gesehen hat.
+func contextNotice(locale string, dropped int, truncated bool) string {
+	var notes []string
+	if dropped > 0 {
+		notes = append(notes, "_"+i18n.T(locale, "gpt.history_dropped", dropped)+"_")
+	}
+	if truncated {
+		notes = append(notes, "_"+i18n.T(locale, "gpt.message_truncated")+"_")
+	}
+	return strings.Join(notes, "\n")
+}
+
+// speaker ist der Name vor der Nachricht im Verlauf. Die Events API liefert keinen user_name,
+// deshalb kommt er aus dem Benutzerverzeichnis.
+func speaker(event slack.Event) string {
+	if name := slackUser.DisplayName(event.User); name != event.User || event.UserName == "" {
+		return name
+	}
+	return event.UserName
+}
+
+// fail ersetzt die "thinking"-Nachricht durch einen Hinweis auf den Fehler und gibt ihn zurück.
+func (c *Chat) fail(ctx context.Context, event slack.Event, err error) (slack.Response, error) {
+	response, updateErr := slack.Instance.ChangeMessage(ctx, event.Timestamp, event.Channel, event.User, slack.GetSimpleMessage(event.User, event.Channel, i18n.T(slackUser.Locale(event.User), "error.generic", err.Error())))
+	if updateErr != nil {
+		log.Printf("Fehler beim Aktualisieren der Antwort: %v", updateErr)
+	}
+	return response, err
 }
 
 func (c *Chat) returnSlackMessage(gptResponse GptResponse) slack.Message {
@@ -198,12 +319,11 @@ func (c *Chat) returnSlackMessage(gptResponse GptResponse) slack.Message {
 	return slack.ReturnSlackMessage(input)
 }
 
-var conversationStorage map[string][]Message
-
 func NewChat(eventManager *system.EventManager) Chat {
 	chat := Chat{
 		resty:        resty.New(),
 		eventManager: eventManager,
+		replies:      NewReplySettings(),
 		cancel:       false,
 	}
 
@@ -211,5 +331,41 @@ func NewChat(eventManager *system.EventManager) Chat {
 }
 
 func GetConversations() map[string][]Message {
-	return conversationStorage
+	conversations, err := Conversations().All()
+	if err != nil {
+		log.Printf("Fehler beim Laden der Verläufe: %v", err)
+	}
+	return conversations
+}
+
+// ClearConversation löscht den Verlauf des Kanals und aller seiner Threads.
+func ClearConversation(channel string) {
+	if err := Conversations().Clear(channel); err != nil {
+		log.Printf("Fehler beim Löschen des Verlaufs von %s: %v", channel, err)
+	}
+}
+
+func usageKey(user string, month time.Time) string {
+	return "gpt:usage:" + user + ":" + month.Format("2006-01")
+}
+
+func recordUsage(user string, at time.Time) {
+	if _, err := system.RedisInstance().Incr(usageKey(user, at)); err != nil {
+		log.Printf("Fehler beim Zählen der GPT-Anfragen: %v", err)
+	}
+}
+
+// Usage liefert die Zahl der GPT-Anfragen des Users im Monat von month.
+func Usage(user string, month time.Time) int {
+	var count int
+	if err := system.RedisInstance().Get(usageKey(user, month), "", &count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// HasConversation meldet, ob der Bot im Thread bereits einen Verlauf führt.
+func HasConversation(channel string, threadTs string) bool {
+	messages, err := Conversations().Messages(ConversationKey(channel, threadTs))
+	return err == nil && len(messages) > 0
 }
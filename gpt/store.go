@@ -0,0 +1,225 @@
+package gpt
+
+import (
+	"encoding/json"
+	"go-slack-ics/system"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxConversationMessages begrenzt den gespeicherten Verlauf, ältere Nachrichten fallen
+	// heraus. Wie viel davon GPT sieht, bestimmt FitContext.
+	maxConversationMessages = 100
+	// defaultConversationTTL gilt ohne GPT_CONVERSATION_TTL. Jede neue Nachricht verlängert sie.
+	defaultConversationTTL = 24 * time.Hour
+	conversationPrefix     = "gpt:conversation:"
+)
+
+// ConversationStore speichert die Verläufe nach ConversationKey. Append hängt atomar an, kürzt
+// auf die letzten maxConversationMessages und verlängert die TTL des Verlaufs.
+type ConversationStore interface {
+	Append(key string, message Message) error
+	Messages(key string) ([]Message, error)
+	// Clear löscht den Verlauf des Kanals und aller seiner Threads.
+	Clear(channel string) error
+	All() (map[string][]Message, error)
+}
+
+var (
+	conversationMu    sync.Mutex
+	conversationStore ConversationStore
+)
+
+// Conversations liefert den Speicher aus GPT_CONVERSATION_STORE: "memory" hält die Verläufe im
+// Prozess, sonst liegen sie in Redis und überstehen einen Neustart. GPT_CONVERSATION_TTL
+// (z. B. "12h") legt fest, wie lange ein Verlauf ohne neue Nachricht bleibt.
+func Conversations() ConversationStore {
+	conversationMu.Lock()
+	defer conversationMu.Unlock()
+	if conversationStore == nil {
+		ttl := defaultConversationTTL
+		if parsed, err := time.ParseDuration(os.Getenv("GPT_CONVERSATION_TTL")); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+		if os.Getenv("GPT_CONVERSATION_STORE") == "memory" {
+			conversationStore = NewMemoryStore(ttl)
+		} else {
+			conversationStore = NewRedisStore(system.RedisInstance(), ttl)
+		}
+	}
+	return conversationStore
+}
+
+// SetConversationStore ersetzt den Speicher, z. B. durch einen MemoryStore in Tests.
+func SetConversationStore(store ConversationStore) {
+	conversationMu.Lock()
+	defer conversationMu.Unlock()
+	conversationStore = store
+}
+
+// belongsTo meldet, ob key der Verlauf des Kanals oder eines seiner Threads ist.
+func belongsTo(key string, channel string) bool {
+	return key == channel || strings.HasPrefix(key, channel+":")
+}
+
+// MemoryStore hält die Verläufe im Prozess. Abgelaufene Verläufe werden beim nächsten Zugriff
+// entfernt.
+type MemoryStore struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	items map[string]*memoryConversation
+}
+
+type memoryConversation struct {
+	messages []Message
+	expires  time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, items: make(map[string]*memoryConversation)}
+}
+
+func (s *MemoryStore) Append(key string, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+
+	conversation, ok := s.items[key]
+	if !ok {
+		conversation = &memoryConversation{}
+		s.items[key] = conversation
+	}
+	conversation.messages = append(conversation.messages, message)
+	if n := len(conversation.messages); n > maxConversationMessages {
+		conversation.messages = append([]Message(nil), conversation.messages[n-maxConversationMessages:]...)
+	}
+	conversation.expires = time.Now().Add(s.ttl)
+	return nil
+}
+
+func (s *MemoryStore) Messages(key string) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+
+	if conversation, ok := s.items[key]; ok {
+		return append([]Message(nil), conversation.messages...), nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) Clear(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.items {
+		if belongsTo(key, channel) {
+			delete(s.items, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) All() (map[string][]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+
+	conversations := make(map[string][]Message, len(s.items))
+	for key, conversation := range s.items {
+		conversations[key] = append([]Message(nil), conversation.messages...)
+	}
+	return conversations, nil
+}
+
+// expire entfernt abgelaufene Verläufe, s.mu muss gehalten werden.
+func (s *MemoryStore) expire(now time.Time) {
+	for key, conversation := range s.items {
+		if now.After(conversation.expires) {
+			delete(s.items, key)
+		}
+	}
+}
+
+// appendScript hängt die Nachricht an, kürzt die Liste und setzt die TTL in einem Schritt, damit
+// parallele Antworten im selben Kanal sich nicht gegenseitig Nachrichten abschneiden.
+const appendScript = `
+redis.call("RPUSH", KEYS[1], ARGV[1])
+redis.call("LTRIM", KEYS[1], -tonumber(ARGV[2]), -1)
+if tonumber(ARGV[3]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return redis.call("LLEN", KEYS[1])
+`
+
+// RedisStore legt jeden Verlauf als Liste mit JSON-Nachrichten unter gpt:conversation:<key> ab.
+type RedisStore struct {
+	redis *system.Redis
+	ttl   time.Duration
+}
+
+func NewRedisStore(redis *system.Redis, ttl time.Duration) *RedisStore {
+	return &RedisStore{redis: redis, ttl: ttl}
+}
+
+func (s *RedisStore) Append(key string, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.Eval(appendScript, []string{conversationPrefix + key}, string(data), maxConversationMessages, s.ttl.Milliseconds())
+	return err
+}
+
+func (s *RedisStore) Messages(key string) ([]Message, error) {
+	entries, err := s.redis.LRange(conversationPrefix+key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		var message Message
+		if err := json.Unmarshal([]byte(entry), &message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (s *RedisStore) Clear(channel string) error {
+	keys, err := s.redis.Scan(conversationPrefix + channel + ":*")
+	if err != nil {
+		return err
+	}
+	for _, key := range append(keys, conversationPrefix+channel) {
+		if err := s.redis.Del(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) All() (map[string][]Message, error) {
+	keys, err := s.redis.Scan(conversationPrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make(map[string][]Message, len(keys))
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, conversationPrefix)
+		messages, err := s.Messages(name)
+		if err != nil {
+			return nil, err
+		}
+		// Zwischen SCAN und LRANGE abgelaufen
+		if len(messages) > 0 {
+			conversations[name] = messages
+		}
+	}
+	return conversations, nil
+}
@@ -0,0 +1,193 @@
+package gpt
+
+import (
+	"fmt"
+	"go-slack-ics/system"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// backend ist ein ConversationStore unter Test. advance lässt die Zeit um d vergehen.
+type backend struct {
+	store   ConversationStore
+	ttl     time.Duration
+	advance func(d time.Duration)
+	// redis ist nur beim RedisStore gesetzt und prüft die TTL der Keys direkt.
+	redis *miniredis.Miniredis
+}
+
+var backends = []struct {
+	name string
+	new  func(t *testing.T) backend
+}{
+	{"memory", func(t *testing.T) backend {
+		// MemoryStore arbeitet mit der echten Uhr, die TTL ist deshalb kurz
+		ttl := 200 * time.Millisecond
+		return backend{store: NewMemoryStore(ttl), ttl: ttl, advance: time.Sleep}
+	}},
+	{"redis", func(t *testing.T) backend {
+		server := miniredis.RunT(t)
+		t.Setenv("REDIS_ADDR", server.Addr())
+		ttl := time.Hour
+		return backend{store: NewRedisStore(system.NewRedis(), ttl), ttl: ttl, advance: server.FastForward, redis: server}
+	}},
+}
+
+// forEachBackend führt test für jeden Speicher aus.
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	for _, tt := range backends {
+		t.Run(tt.name, func(t *testing.T) {
+			test(t, tt.new(t))
+		})
+	}
+}
+
+func message(text string) Message {
+	return Message{Role: "user", Content: text}
+}
+
+func TestStoreParallelAppend(t *testing.T) {
+	const channels, writers, perWriter = 5, 4, 20
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		var wg sync.WaitGroup
+		errs := make(chan error, channels*writers*perWriter)
+		for c := 0; c < channels; c++ {
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(key string, writer int) {
+					defer wg.Done()
+					for i := 0; i < perWriter; i++ {
+						errs <- b.store.Append(key, message(fmt.Sprintf("%d/%d", writer, i)))
+					}
+				}(fmt.Sprintf("C%d", c), w)
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for c := 0; c < channels; c++ {
+			messages, err := b.store.Messages(fmt.Sprintf("C%d", c))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != writers*perWriter {
+				t.Fatalf("C%d: %d Nachrichten, erwartet %d", c, len(messages), writers*perWriter)
+			}
+			// Die Nachrichten eines Schreibers bleiben in ihrer Reihenfolge
+			next := make(map[int]int)
+			for _, m := range messages {
+				var writer, i int
+				fmt.Sscanf(m.Content, "%d/%d", &writer, &i)
+				if i != next[writer] {
+					t.Fatalf("C%d: %s, erwartet %d/%d", c, m.Content, writer, next[writer])
+				}
+				next[writer]++
+			}
+		}
+	})
+}
+
+func TestStoreTrim(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		for i := 0; i < maxConversationMessages+30; i++ {
+			if err := b.store.Append("C1", message(fmt.Sprint(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		messages, _ := b.store.Messages("C1")
+		if len(messages) != maxConversationMessages {
+			t.Fatalf("%d Nachrichten, erwartet %d", len(messages), maxConversationMessages)
+		}
+		if messages[0].Content != "30" || messages[len(messages)-1].Content != fmt.Sprint(maxConversationMessages+29) {
+			t.Fatalf("Verlauf von %s bis %s, erwartet die letzten %d", messages[0].Content, messages[len(messages)-1].Content, maxConversationMessages)
+		}
+
+		// Auch parallel bleibt es bei maxConversationMessages
+		var wg sync.WaitGroup
+		for w := 0; w < 3; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < maxConversationMessages/2; i++ {
+					b.store.Append("C2", message("x"))
+				}
+			}()
+		}
+		wg.Wait()
+		if messages, _ := b.store.Messages("C2"); len(messages) != maxConversationMessages {
+			t.Fatalf("parallel: %d Nachrichten, erwartet %d", len(messages), maxConversationMessages)
+		}
+	})
+}
+
+func TestStoreTTL(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		b.store.Append("C1", message("alt"))
+		b.store.Append("C2", message("verlängert"))
+		if b.redis != nil {
+			if ttl := b.redis.TTL(conversationPrefix + "C1"); ttl != b.ttl {
+				t.Fatalf("TTL des Keys %s, erwartet %s", ttl, b.ttl)
+			}
+		}
+
+		b.advance(b.ttl * 6 / 10)
+		b.store.Append("C2", message("neu"))
+		b.advance(b.ttl * 6 / 10)
+
+		if messages, _ := b.store.Messages("C1"); len(messages) != 0 {
+			t.Fatalf("abgelaufener Verlauf hat noch %d Nachrichten", len(messages))
+		}
+		if messages, _ := b.store.Messages("C2"); len(messages) != 2 {
+			t.Fatalf("verlängerter Verlauf hat %d Nachrichten, erwartet 2", len(messages))
+		}
+		all, err := b.store.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := all["C1"]; ok || len(all) != 1 {
+			t.Fatalf("All liefert %v", all)
+		}
+	})
+}
+
+func TestStoreClearAndAll(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		for _, key := range []string{"C1", "C1:1700000000.000100", "C10", "D1"} {
+			b.store.Append(key, message(key))
+		}
+		if err := b.store.Clear("C1"); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := b.store.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 || len(all["C10"]) != 1 || all["D1"][0].Content != "D1" {
+			t.Fatalf("nach Clear(C1): %v", all)
+		}
+		if messages, _ := b.store.Messages("C1:1700000000.000100"); len(messages) != 0 {
+			t.Fatal("Thread-Verlauf nach Clear des Kanals noch vorhanden")
+		}
+	})
+}
+
+func TestRedisStoreWithoutTTL(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("REDIS_ADDR", server.Addr())
+	store := NewRedisStore(system.NewRedis(), 0)
+
+	store.Append("C1", message("bleibt"))
+	if ttl := server.TTL(conversationPrefix + "C1"); ttl != 0 {
+		t.Fatalf("TTL %s, ohne ttl darf appendScript keine setzen", ttl)
+	}
+}
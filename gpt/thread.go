@@ -0,0 +1,71 @@
+package gpt
+
+import (
+	"go-slack-ics/slack"
+	"os"
+	"strings"
+)
+
+const (
+	// ReplyThread antwortet im Thread der auslösenden Nachricht.
+	ReplyThread = "thread"
+	// ReplyChannel antwortet direkt im Kanal, wie bisher.
+	ReplyChannel = "channel"
+)
+
+// ReplySettings legt pro Kanal fest, ob der Bot im Thread oder im Kanal antwortet.
+type ReplySettings struct {
+	Default  string
+	Channels map[string]string
+}
+
+// NewReplySettings liest GPT_REPLY_IN (thread oder channel, Standard thread) und
+// GPT_REPLY_CHANNELS mit Ausnahmen pro Kanal, z. B. "C0123=channel,C0456=thread".
+func NewReplySettings() ReplySettings {
+	settings := ReplySettings{
+		Default:  os.Getenv("GPT_REPLY_IN"),
+		Channels: make(map[string]string),
+	}
+	if settings.Default != ReplyChannel {
+		settings.Default = ReplyThread
+	}
+
+	for _, entry := range strings.Split(os.Getenv("GPT_REPLY_CHANNELS"), ",") {
+		channel, mode, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || (mode != ReplyThread && mode != ReplyChannel) {
+			continue
+		}
+		settings.Channels[channel] = mode
+	}
+	return settings
+}
+
+// ThreadTs liefert den Thread, in dem die Antwort auf event erscheinen soll, oder "" für den
+// Kanal. Nachrichten, die schon in einem Thread stehen, werden immer dort beantwortet.
+func (r ReplySettings) ThreadTs(event slack.Event) string {
+	if event.ThreadTs != "" {
+		return event.ThreadTs
+	}
+
+	mode, ok := r.Channels[event.Channel]
+	if !ok {
+		mode = r.Default
+		// In Direktnachrichten sind Threads unüblich
+		if event.ChannelType == "im" {
+			mode = ReplyChannel
+		}
+	}
+	if mode == ReplyThread {
+		return event.Timestamp
+	}
+	return ""
+}
+
+// ConversationKey trennt die Verläufe paralleler Threads eines Kanals. Antworten im Kanal
+// teilen sich einen Verlauf pro Kanal.
+func ConversationKey(channel string, threadTs string) string {
+	if threadTs == "" {
+		return channel
+	}
+	return channel + ":" + threadTs
+}
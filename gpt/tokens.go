@@ -0,0 +1,203 @@
+package gpt
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// answerReserve bleibt im Kontextfenster für die Antwort frei, wenn GPT_CONTEXT_TOKENS fehlt.
+const answerReserve = 1024
+
+// contextWindows sind die Kontextfenster der Modelle in Tokens. Der erste passende Präfix
+// gewinnt, daher stehen die spezielleren Namen vorn.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-1106", 128000},
+	{"gpt-4-0125", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo-0301", 4096},
+	{"gpt-3.5-turbo-0613", 4096},
+	{"gpt-3.5-turbo", 16385},
+}
+
+// ContextWindow liefert das Kontextfenster von model, für unbekannte Modelle vorsichtig 4096.
+func ContextWindow(model string) int {
+	for _, window := range contextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return 4096
+}
+
+// ContextBudget ist die Zahl der Tokens, die der Verlauf samt Systemprompt belegen darf:
+// GPT_CONTEXT_TOKENS oder das Kontextfenster abzüglich answerReserve. Mehr als das Fenster
+// des Modells wird es nie.
+func ContextBudget(model string) int {
+	window := ContextWindow(model)
+	if budget, err := strconv.Atoi(os.Getenv("GPT_CONTEXT_TOKENS")); err == nil && budget > 0 {
+		if budget > window {
+			return window
+		}
+		return budget
+	}
+	return window - answerReserve
+}
+
+// encodings hält die bereits geladenen BPE-Vokabulare aus tiktoken-go nach Namen.
+var (
+	encodingMu sync.Mutex
+	encodings  = make(map[string]*tiktoken.Tiktoken)
+)
+
+func init() {
+	// Die Vokabulare sind eingebettet, tiktoken-go lädt sonst beim ersten Aufruf aus dem Netz
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// encodingName liefert das Vokabular von model wie tiktoken. Unbekannte Modelle zählen mit
+// cl100k_base, neuere gpt-4o-Varianten mit o200k_base.
+func encodingName(model string) string {
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name
+	}
+	for prefix, name := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return name
+		}
+	}
+	if strings.HasPrefix(model, "gpt-4o") {
+		return tiktoken.MODEL_O200K_BASE
+	}
+	return tiktoken.MODEL_CL100K_BASE
+}
+
+// encoding lädt das Vokabular von model beim ersten Aufruf, das dauert einige hundert
+// Millisekunden.
+func encoding(model string) (*tiktoken.Tiktoken, error) {
+	name := encodingName(model)
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// CountTokens zählt die Tokens von text mit dem BPE-Vokabular von model. Fehlt das Vokabular,
+// zählt jedes Byte als Token; mehr Tokens als Bytes kann kein Vokabular ergeben.
+func CountTokens(model string, text string) int {
+	enc, err := encoding(model)
+	if err != nil {
+		log.Printf("Tokenizer für %s nicht verfügbar, zähle Bytes: %v", model, err)
+		return len(text)
+	}
+	return len(enc.EncodeOrdinary(text))
+}
+
+// messageOverhead sind die Tokens, die das Chat-Format pro Nachricht zusätzlich belegt, und
+// replyOverhead die für den Beginn der Antwort (siehe OpenAI-Cookbook zum Zählen von Tokens).
+func messageOverhead(model string) int {
+	if strings.HasPrefix(model, "gpt-3.5-turbo-0301") {
+		return 4
+	}
+	return 3
+}
+
+const replyOverhead = 3
+
+func countMessage(model string, message Message) int {
+	return messageOverhead(model) + CountTokens(model, message.Role) + CountTokens(model, message.Content)
+}
+
+// FitContext kürzt den Verlauf auf budget Tokens. Die Systemnachrichten am Anfang bleiben immer
+// erhalten, vom Rest die neuesten Nachrichten, soweit sie passen. Die letzte Nachricht fehlt
+// nie, ist sie allein zu lang, wird ihr Inhalt gekürzt. dropped zählt die weggelassenen
+// Nachrichten, truncated meldet die gekürzte letzte Nachricht.
+func FitContext(model string, budget int, messages []Message) (fitted []Message, dropped int, truncated bool) {
+	system := 0
+	for system < len(messages) && messages[system].Role == "system" {
+		system++
+	}
+
+	remaining := budget - replyOverhead
+	for _, message := range messages[:system] {
+		remaining -= countMessage(model, message)
+	}
+
+	history := messages[system:]
+	start := len(history)
+	for start > 0 {
+		message := history[start-1]
+		cost := countMessage(model, message)
+		if cost > remaining {
+			if start == len(history) {
+				message.Content = truncateTokens(model, message.Content, remaining-(cost-CountTokens(model, message.Content)))
+				history = append(append([]Message(nil), history[:start-1]...), message)
+				truncated = true
+				start--
+			}
+			break
+		}
+		remaining -= cost
+		start--
+	}
+
+	fitted = append(append([]Message(nil), messages[:system]...), history[start:]...)
+	return fitted, start, truncated
+}
+
+// truncateTokens kürzt text auf höchstens max Tokens und markiert die Kürzung mit "…".
+func truncateTokens(model string, text string, max int) string {
+	if max <= 1 {
+		return "…"
+	}
+	enc, err := encoding(model)
+	if err != nil {
+		// Ein Token pro Byte wie in CountTokens
+		return trimInvalidUTF8(text[:max-1]) + "…"
+	}
+
+	tokens := enc.EncodeOrdinary(text)
+	if len(tokens) <= max {
+		return text
+	}
+	// An der Schnittstelle kann "…" mit dem Rest zu anderen Tokens verschmelzen
+	for n := max - 1; n > 0; n-- {
+		truncated := trimInvalidUTF8(enc.Decode(tokens[:n])) + "…"
+		if len(enc.EncodeOrdinary(truncated)) <= max {
+			return truncated
+		}
+	}
+	return "…"
+}
+
+// trimInvalidUTF8 entfernt ein angeschnittenes Zeichen am Ende. Tokens trennen Umlaute und
+// Emoji mitten im Zeichen.
+func trimInvalidUTF8(text string) string {
+	for len(text) > 0 {
+		r, size := utf8.DecodeLastRuneInString(text)
+		if r != utf8.RuneError || size > 1 {
+			break
+		}
+		text = text[:len(text)-1]
+	}
+	return text
+}
@@ -0,0 +1,147 @@
+package gpt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestCountTokensCookbook prüft gegen die Zahlen aus dem OpenAI-Cookbook "How to count tokens
+// with tiktoken".
+func TestCountTokensCookbook(t *testing.T) {
+	tests := []struct {
+		text          string
+		cl100k, o200k int
+	}{
+		{"antidisestablishmentarianism", 6, 6},
+		{"2 + 2 = 4", 7, 7},
+		{"お誕生日おめでとう", 9, 8},
+	}
+	for _, tt := range tests {
+		if got := CountTokens("gpt-4", tt.text); got != tt.cl100k {
+			t.Errorf("cl100k %q: %d Tokens, erwartet %d", tt.text, got, tt.cl100k)
+		}
+		if got := CountTokens("gpt-4o", tt.text); got != tt.o200k {
+			t.Errorf("o200k %q: %d Tokens, erwartet %d", tt.text, got, tt.o200k)
+		}
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		name, text    string
+		cl100k, o200k int
+	}{
+		{"englisch", "Hello, world!", 4, 4},
+		{"deutsch", "Die Mülltonne wird morgen früh um 6 Uhr abgeholt.", 18, 14},
+		{"umlaute", "Straßenreinigung: Größere Gegenstände bitte nicht neben die Tonne stellen!", 22, 18},
+		{"code", "func main() {\n\tfmt.Println(\"hallo\")\n}\n", 11, 11},
+		{"emoji", "🗑️♻️🚮", 10, 9},
+		{"zwj-emoji", "👨‍👩‍👧‍👦", 18, 11},
+		{"special token als text", "<|endoftext|>", 7, 7},
+		{"leer", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountTokens("gpt-3.5-turbo", tt.text); got != tt.cl100k {
+				t.Errorf("cl100k: %d Tokens, erwartet %d", got, tt.cl100k)
+			}
+			if got := CountTokens("gpt-4o-mini", tt.text); got != tt.o200k {
+				t.Errorf("o200k: %d Tokens, erwartet %d", got, tt.o200k)
+			}
+		})
+	}
+}
+
+func TestEncodingName(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o":                 "o200k_base",
+		"gpt-4o-2024-08-06":      "o200k_base",
+		"gpt-4o-irgendwann":      "o200k_base",
+		"gpt-4":                  "cl100k_base",
+		"gpt-4-turbo-2024-04-09": "cl100k_base",
+		"gpt-3.5-turbo-0125":     "cl100k_base",
+		"unbekannt":              "cl100k_base",
+	}
+	for model, want := range tests {
+		if got := encodingName(model); got != want {
+			t.Errorf("%s: %s, erwartet %s", model, got, want)
+		}
+	}
+}
+
+func TestTruncateTokens(t *testing.T) {
+	text := "Größere Gegenstände 🗑️♻️ bitte nicht neben die Tonne stellen, 👨‍👩‍👧‍👦 danke!"
+	for _, model := range []string{"gpt-4", "gpt-4o"} {
+		total := CountTokens(model, text)
+		for max := 0; max < total; max++ {
+			got := truncateTokens(model, text, max)
+			if !utf8.ValidString(got) || !strings.HasSuffix(got, "…") {
+				t.Fatalf("%s, max %d: %q", model, max, got)
+			}
+			if n := CountTokens(model, got); max > 0 && n > max {
+				t.Fatalf("%s, max %d: %q hat %d Tokens", model, max, got, n)
+			}
+			if !strings.HasPrefix(text, strings.TrimSuffix(got, "…")) {
+				t.Fatalf("%s, max %d: %q ist kein Anfang des Texts", model, max, got)
+			}
+		}
+		if got := truncateTokens(model, text, total); got != text {
+			t.Fatalf("%s: passender Text gekürzt zu %q", model, got)
+		}
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	const model = "gpt-4o"
+	system := Message{Role: "system", Content: "Du bist ein hilfreicher Assistent."}
+	history := []Message{system}
+	for i := 0; i < 20; i++ {
+		history = append(history, Message{Role: "user", Content: fmt.Sprintf("Nachricht %d über die Biotonne", i)})
+	}
+	cost := func(messages []Message) int {
+		total := replyOverhead
+		for _, message := range messages {
+			total += countMessage(model, message)
+		}
+		return total
+	}
+
+	t.Run("passt", func(t *testing.T) {
+		fitted, dropped, truncated := FitContext(model, cost(history), history)
+		if len(fitted) != len(history) || dropped != 0 || truncated {
+			t.Fatalf("%d Nachrichten, %d weggelassen, gekürzt %v", len(fitted), dropped, truncated)
+		}
+	})
+
+	t.Run("ältere fallen weg", func(t *testing.T) {
+		budget := cost(append([]Message{system}, history[len(history)-5:]...))
+		fitted, dropped, truncated := FitContext(model, budget, history)
+		if len(fitted) != 6 || dropped != 15 || truncated {
+			t.Fatalf("%d Nachrichten, %d weggelassen, gekürzt %v", len(fitted), dropped, truncated)
+		}
+		if fitted[0] != system || fitted[5] != history[len(history)-1] {
+			t.Fatalf("falsche Nachrichten behalten: %v", fitted)
+		}
+		if n := cost(fitted); n > budget {
+			t.Fatalf("%d Tokens, Budget %d", n, budget)
+		}
+	})
+
+	t.Run("letzte Nachricht gekürzt", func(t *testing.T) {
+		long := Message{Role: "user", Content: strings.Repeat("Wann kommt die gelbe Tonne? 🚮 ", 50)}
+		messages := []Message{system, {Role: "user", Content: "alt"}, long}
+		budget := cost([]Message{system}) + 40
+		fitted, dropped, truncated := FitContext(model, budget, messages)
+		if len(fitted) != 2 || dropped != 1 || !truncated {
+			t.Fatalf("%d Nachrichten, %d weggelassen, gekürzt %v", len(fitted), dropped, truncated)
+		}
+		if n := cost(fitted); n > budget {
+			t.Fatalf("%d Tokens, Budget %d", n, budget)
+		}
+		if !strings.HasSuffix(fitted[1].Content, "…") {
+			t.Fatalf("gekürzte Nachricht %q ohne Markierung", fitted[1].Content)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+// Package home baut den App-Home-Tab: die nächsten Abholungen im Wochendienst, die zuletzt
+// erzeugten Bilder und die GPT-Nutzung des Users.
+package home
+
+import (
+	"context"
+	"fmt"
+	"go-slack-ics/calendar"
+	"go-slack-ics/gpt"
+	"go-slack-ics/i18n"
+	"go-slack-ics/rotation"
+	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+const (
+	ActionAcknowledge = "home_ack"
+	ActionSwap        = "home_swap"
+	ActionClearGPT    = "home_gpt_clear"
+)
+
+const (
+	maxDuties   = 5
+	shownImages = 5
+	dutyHorizon = 42 * 24 * time.Hour
+)
+
+// Register veröffentlicht den Tab bei app_home_opened und meldet die Buttons an.
+func Register(events *slack.EventDispatcher, interactions *slack.InteractionDispatcher) {
+	events.On("app_home_opened", func(payload slack.Payload) {
+		if payload.Event.Tab != "home" {
+			return
+		}
+		ctx := slack.WithTeam(context.Background(), payload.TeamID)
+		if err := Publish(ctx, payload.Event.User, payload.Event.Channel); err != nil {
+			log.Printf("Fehler beim Veröffentlichen des App-Home für %s: %v", payload.Event.User, err)
+		}
+	})
+
+	interactions.Action(ActionAcknowledge, acknowledge)
+	interactions.Action(ActionSwap, swap)
+	interactions.Action(ActionClearGPT, clearConversation)
+}
+
+// Publish baut den Tab für den User neu auf. channel ist die Direktnachricht mit dem Bot, deren
+// GPT-Verlauf der Tab löschen kann.
+func Publish(ctx context.Context, user string, channel string) error {
+	blocks := Build(user, channel, time.Now())
+	if err := slack.ValidateBlocks(blocks, slack.MaxViewBlocks); err != nil {
+		return err
+	}
+
+	_, err := slack.Instance.PublishView(ctx, user, slack.View{
+		Type:            "home",
+		Blocks:          blocks,
+		PrivateMetadata: channel,
+	})
+	return err
+}
+
+func Build(user string, channel string, now time.Time) []slack.Block {
+	locale := slackUser.Locale(user)
+	b := slack.NewBlocks().Header(i18n.T(locale, "home.title"))
+	if position, ok := rotation.Position(user); ok {
+		b.Context(slack.Mrkdwn(i18n.T(locale, "home.position", position, len(rotation.Order), rotation.OnDuty(now))))
+	} else {
+		b.Context(slack.Mrkdwn(i18n.T(locale, "home.not_in_rotation")))
+	}
+
+	duties := upcomingDuties(user, now)
+	if len(duties) == 0 {
+		b.Section(i18n.T(locale, "home.no_duties"))
+	}
+	for _, e := range duties {
+		text := fmt.Sprintf("*%s* » %s", i18n.Date(locale, *e.Start), escape(e.Summary))
+		if calendar.IsAcknowledged(e) {
+			b.Section(text + " :white_check_mark:")
+			continue
+		}
+		b.SectionWithAccessory(text, slack.NewButton(ActionAcknowledge, i18n.T(locale, "calendar.done"), e.Uid).Primary())
+	}
+	if len(duties) > 0 {
+		week := rotation.WeekStart(*duties[0].Start)
+		b.Actions(slack.NewButton(ActionSwap, i18n.T(locale, "home.swap", i18n.DayMonth(locale, week)), week.Format("2006-01-02")).
+			WithConfirm(i18n.T(locale, "home.swap_title"), i18n.T(locale, "home.swap_text", i18n.Date(locale, week)),
+				i18n.T(locale, "home.swap_confirm"), i18n.T(locale, "home.cancel")))
+	}
+
+	b.Divider().Header(i18n.T(locale, "home.images"))
+	images, err := RecentImages(user, shownImages)
+	if err != nil {
+		log.Printf("Fehler beim Laden der Bilder von %s: %v", user, err)
+	}
+	if len(images) == 0 {
+		b.Context(slack.Mrkdwn(i18n.T(locale, "home.no_images")))
+	}
+	for _, image := range images {
+		text := escape(image.Prompt)
+		if image.Permalink != "" {
+			text = fmt.Sprintf("<%s|%s>", image.Permalink, text)
+		}
+		b.Section(text).Context(slack.Mrkdwn(image.Source + " · " + i18n.DateTime(locale, image.At)))
+	}
+
+	b.Divider().Header(i18n.T(locale, "home.gpt")).
+		Section(i18n.T(locale, "home.gpt_usage", gpt.Usage(user, now)))
+	if channel != "" {
+		b.Actions(slack.NewButton(ActionClearGPT, i18n.T(locale, "home.gpt_clear"), channel).Danger().
+			WithConfirm(i18n.T(locale, "home.gpt_clear"), i18n.T(locale, "home.gpt_clear_text"),
+				i18n.T(locale, "home.gpt_clear_button"), i18n.T(locale, "home.cancel")))
+	}
+	return b.Blocks()
+}
+
+// upcomingDuties liefert die nächsten Termine aller Quellen, für die der User Dienst hat.
+func upcomingDuties(user string, now time.Time) []gocal.Event {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var duties []gocal.Event
+	for _, source := range calendar.Sources {
+		for _, e := range calendar.DefaultStore.Events(source.Name, today, today.Add(dutyHorizon)) {
+			if rotation.OnDuty(*e.Start) == user {
+				duties = append(duties, e)
+			}
+		}
+	}
+	sort.Slice(duties, func(i, j int) bool {
+		return duties[i].Start.Before(*duties[j].Start)
+	})
+	if len(duties) > maxDuties {
+		duties = duties[:maxDuties]
+	}
+	return duties
+}
+
+func acknowledge(ctx context.Context, i *slack.Interaction) error {
+	if e, ok := calendar.FindEvent(i.Action.Value); ok && !calendar.IsAcknowledged(e) {
+		calendar.RecordAcknowledgement(e, i.User.ID)
+	}
+	return republish(ctx, i)
+}
+
+func swap(ctx context.Context, i *slack.Interaction) error {
+	week, err := time.Parse("2006-01-02", i.Action.Value)
+	if err != nil {
+		return err
+	}
+	// Der Tab kann veraltet sein, abgeben darf nur, wer die Woche gerade hat
+	if rotation.OnDuty(week) == i.User.ID {
+		if _, err := rotation.Swap(week); err != nil {
+			return err
+		}
+		next := rotation.OnDuty(week)
+		locale := slackUser.Locale(next)
+		message := slack.GetSimpleMessage(next, next, i18n.T(locale, "home.swapped_to_you", i.User.ID, i18n.Date(locale, week)))
+		if _, err := slack.Instance.SendMessage(ctx, next, next, message); err != nil {
+			log.Printf("Fehler beim Benachrichtigen von %s: %v", next, err)
+		}
+	}
+	return republish(ctx, i)
+}
+
+func clearConversation(ctx context.Context, i *slack.Interaction) error {
+	gpt.ClearConversation(i.Action.Value)
+	return republish(ctx, i)
+}
+
+func republish(ctx context.Context, i *slack.Interaction) error {
+	channel := ""
+	if i.View != nil {
+		channel = i.View.PrivateMetadata
+	}
+	return Publish(ctx, i.User.ID, channel)
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
@@ -0,0 +1,62 @@
+package home
+
+import (
+	"encoding/json"
+	"go-slack-ics/system"
+	"log"
+	"time"
+)
+
+// maxImages ist die Zahl der Bilder, die pro User gemerkt werden.
+const maxImages = 10
+
+// Image ist ein erzeugtes Bild für die Liste im App-Home.
+type Image struct {
+	Prompt    string    `json:"prompt"`
+	Permalink string    `json:"permalink"`
+	Source    string    `json:"source"`
+	At        time.Time `json:"at"`
+}
+
+func imagesKey(user string) string {
+	return "home:images:" + user
+}
+
+// RecordImage merkt sich ein erzeugtes Bild des Users.
+func RecordImage(user string, image Image) {
+	if user == "" {
+		return
+	}
+	data, err := json.Marshal(image)
+	if err != nil {
+		log.Printf("Fehler beim Speichern des Bildes: %v", err)
+		return
+	}
+
+	redis := system.RedisInstance()
+	if err := redis.LPush(imagesKey(user), data, ""); err != nil {
+		log.Printf("Fehler beim Speichern des Bildes: %v", err)
+		return
+	}
+	if err := redis.LTrim(imagesKey(user), 0, maxImages-1); err != nil {
+		log.Printf("Fehler beim Kürzen der Bilderliste: %v", err)
+	}
+}
+
+// RecentImages liefert die letzten n Bilder des Users, das neueste zuerst.
+func RecentImages(user string, n int) ([]Image, error) {
+	values, err := system.RedisInstance().LRange(imagesKey(user), 0, int64(n-1))
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(values))
+	for _, value := range values {
+		var image Image
+		if err := json.Unmarshal([]byte(value), &image); err != nil {
+			continue
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
@@ -0,0 +1,96 @@
+package i18n
+
+var de = Catalog{
+	"format.date":     "02.01.2006",
+	"format.datetime": "02.01.2006 15:04",
+	"format.daymonth": "02.01.",
+
+	"month.1":  "Januar",
+	"month.2":  "Februar",
+	"month.3":  "März",
+	"month.4":  "April",
+	"month.5":  "Mai",
+	"month.6":  "Juni",
+	"month.7":  "Juli",
+	"month.8":  "August",
+	"month.9":  "September",
+	"month.10": "Oktober",
+	"month.11": "November",
+	"month.12": "Dezember",
+
+	"error.generic": "Da ist leider etwas schiefgegangen: %s",
+
+	"calendar.links":      "*Links*",
+	"calendar.done":       "Erledigt",
+	"calendar.done_by":    ":white_check_mark: Erledigt von <@%s>",
+	"calendar.event_gone": "Diesen Termin gibt es nicht mehr.",
+	"calendar.no_events":  "Keine Termine zwischen %s und %s",
+
+	"stats.title":        "*Abfuhr-Statistik %d*",
+	"stats.user":         "• <@%s>: %d Erinnerungen, %d bestätigt, %d Eskalationen, Ø %.1f h bis zur Bestätigung",
+	"stats.month":        "%s: %d Abholungen, %d verpasst",
+	"stats.missed_total": "Verpasst insgesamt: %d",
+
+	"command.unknown":            "Den Befehl %s kenne ich nicht.",
+	"command.available":          "Verfügbare Befehle: %s",
+	"command.unknown_subcommand": "%s kennt „%s“ nicht.",
+	"command.invalid_args":       "%s\nVerwendung: `%s`",
+	"command.help":               "diese Hilfe",
+
+	"command.abfuhr":             "Abfuhrtermine im Wochendienst",
+	"command.abfuhr.stats":       "Statistik des Jahres",
+	"command.abfuhr.stats.usage": "[Jahr]",
+	"command.abfuhr.erledigt":    "nächste offene Abholung bestätigen",
+	"command.invalid_year":       "„%s“ ist kein Jahr.",
+	"command.language":           "Sprache der Antworten einstellen",
+	"command.language.usage":     "de|en|auto",
+	"command.text_to_image":      "Bild mit Leonardo erzeugen",
+	"command.clipdrop":           "Bild mit Clipdrop erzeugen",
+	"command.image.usage":        "<Beschreibung>",
+
+	"command.stats_unavailable": "Statistik nicht verfügbar: %s",
+	"command.nothing_open":      "Keine offene Abholung gefunden.",
+	"command.done":              "<@%s> hat erledigt: %s",
+	"command.language_set":      "Ich schreibe dir ab jetzt auf Deutsch.",
+	"command.language_reset":    "Die Sprache richtet sich wieder nach deinem Slack-Profil.",
+	"command.language_unknown":  "Diese Sprache kenne ich nicht. Verfügbar: %s",
+
+	"gpt.thinking":          "... denke nach ...",
+	"gpt.creating_image":    "... erstelle Bild ...",
+	"gpt.history_dropped":   "%d ältere Nachrichten passen nicht mehr in den Kontext und wurden nicht berücksichtigt.",
+	"gpt.message_truncated": "Deine Nachricht war zu lang und wurde gekürzt.",
+
+	"image.prompt_missing": "Bitte gib eine Beschreibung für das Bild an.",
+	"image.generating":     "Das Bild wird erzeugt …",
+	"image.uploading":      "Das Bild ist fertig und wird hochgeladen …",
+	"image.done":           "Fertig: %s",
+	"image.done_link":      "Fertig: <%s|%s>",
+
+	"home.title":            "Abfuhr",
+	"home.position":         "Du bist an Position %d von %d im Wochendienst. Diese Woche hat <@%s> Dienst.",
+	"home.not_in_rotation":  "Du bist nicht im Wochendienst eingeteilt.",
+	"home.no_duties":        "Keine Abholungen in den nächsten sechs Wochen.",
+	"home.swap":             "Woche ab %s abgeben",
+	"home.swap_title":       "Dienst tauschen",
+	"home.swap_text":        "Die Woche ab %s übernimmt dann die nächste Person.",
+	"home.swap_confirm":     "Tauschen",
+	"home.cancel":           "Abbrechen",
+	"home.swapped_to_you":   "<@%s> hat dir den Dienst in der Woche ab %s übergeben.",
+	"home.images":           "Bilder",
+	"home.no_images":        "Noch keine Bilder erzeugt.",
+	"home.gpt":              "GPT",
+	"home.gpt_usage":        "Anfragen diesen Monat: *%d*",
+	"home.gpt_clear":        "Verlauf löschen",
+	"home.gpt_clear_text":   "Der Bot vergisst eure bisherige Unterhaltung.",
+	"home.gpt_clear_button": "Löschen",
+
+	"web.hello":              "Hallo Welt!",
+	"web.go":                 "Du bist im Go-Pfad!",
+	"web.not_found":          "Nicht gefunden",
+	"web.client_id_missing":  "SLACK_CLIENT_ID fehlt",
+	"web.install_cancelled":  "Installation abgebrochen: %s",
+	"web.install_link_stale": "Ungültiger oder abgelaufener Installationslink",
+	"web.install_failed":     "Installation fehlgeschlagen: %s",
+	"web.installed":          "Installiert in %s",
+	"web.team_not_allowed":   "Der Workspace %s darf die App nicht installieren",
+}
@@ -0,0 +1,96 @@
+package i18n
+
+var en = Catalog{
+	"format.date":     "Jan 2, 2006",
+	"format.datetime": "Jan 2, 2006 3:04 PM",
+	"format.daymonth": "Jan 2",
+
+	"month.1":  "January",
+	"month.2":  "February",
+	"month.3":  "March",
+	"month.4":  "April",
+	"month.5":  "May",
+	"month.6":  "June",
+	"month.7":  "July",
+	"month.8":  "August",
+	"month.9":  "September",
+	"month.10": "October",
+	"month.11": "November",
+	"month.12": "December",
+
+	"error.generic": "Sorry, something went wrong: %s",
+
+	"calendar.links":      "*Links*",
+	"calendar.done":       "Done",
+	"calendar.done_by":    ":white_check_mark: Done by <@%s>",
+	"calendar.event_gone": "This pickup no longer exists.",
+	"calendar.no_events":  "No pickups between %s and %s",
+
+	"stats.title":        "*Pickup statistics %d*",
+	"stats.user":         "• <@%s>: %d reminders, %d acknowledged, %d escalations, Ø %.1f h until acknowledged",
+	"stats.month":        "%s: %d pickups, %d missed",
+	"stats.missed_total": "Missed in total: %d",
+
+	"command.unknown":            "I don't know the command %s.",
+	"command.available":          "Available commands: %s",
+	"command.unknown_subcommand": "%s has no subcommand “%s”.",
+	"command.invalid_args":       "%s\nUsage: `%s`",
+	"command.help":               "this help",
+
+	"command.abfuhr":             "Pickups in the weekly rotation",
+	"command.abfuhr.stats":       "statistics for the year",
+	"command.abfuhr.stats.usage": "[year]",
+	"command.abfuhr.erledigt":    "acknowledge the next open pickup",
+	"command.invalid_year":       "“%s” is not a year.",
+	"command.language":           "set the language of the replies",
+	"command.language.usage":     "de|en|auto",
+	"command.text_to_image":      "create an image with Leonardo",
+	"command.clipdrop":           "create an image with Clipdrop",
+	"command.image.usage":        "<description>",
+
+	"command.stats_unavailable": "Statistics unavailable: %s",
+	"command.nothing_open":      "No open pickup found.",
+	"command.done":              "<@%s> took care of: %s",
+	"command.language_set":      "From now on I will write to you in English.",
+	"command.language_reset":    "The language follows your Slack profile again.",
+	"command.language_unknown":  "I don't know that language. Available: %s",
+
+	"gpt.thinking":          "... thinking ...",
+	"gpt.creating_image":    "... creating image ...",
+	"gpt.history_dropped":   "%d older messages no longer fit into the context and were left out.",
+	"gpt.message_truncated": "Your message was too long and has been shortened.",
+
+	"image.prompt_missing": "Please describe the image you want.",
+	"image.generating":     "Generating your image …",
+	"image.uploading":      "The image is ready and being uploaded …",
+	"image.done":           "Done: %s",
+	"image.done_link":      "Done: <%s|%s>",
+
+	"home.title":            "Pickups",
+	"home.position":         "You are number %d of %d in the weekly rotation. <@%s> is on duty this week.",
+	"home.not_in_rotation":  "You are not part of the weekly rotation.",
+	"home.no_duties":        "No pickups in the next six weeks.",
+	"home.swap":             "Hand over week of %s",
+	"home.swap_title":       "Swap duty",
+	"home.swap_text":        "The week of %s will go to the next person.",
+	"home.swap_confirm":     "Swap",
+	"home.cancel":           "Cancel",
+	"home.swapped_to_you":   "<@%s> handed the duty for the week of %s over to you.",
+	"home.images":           "Images",
+	"home.no_images":        "No images created yet.",
+	"home.gpt":              "GPT",
+	"home.gpt_usage":        "Requests this month: *%d*",
+	"home.gpt_clear":        "Clear history",
+	"home.gpt_clear_text":   "The bot will forget your conversation so far.",
+	"home.gpt_clear_button": "Clear",
+
+	"web.hello":              "Hello World!",
+	"web.go":                 "You are on the Go path!",
+	"web.not_found":          "Not found",
+	"web.client_id_missing":  "SLACK_CLIENT_ID is missing",
+	"web.install_cancelled":  "Installation cancelled: %s",
+	"web.install_link_stale": "Invalid or expired installation link",
+	"web.install_failed":     "Installation failed: %s",
+	"web.installed":          "Installed in %s",
+	"web.team_not_allowed":   "The workspace %s may not install this app",
+}
@@ -0,0 +1,96 @@
+// Package i18n übersetzt die Texte des Bots. Die Texte liegen pro Sprache in einem Catalog,
+// die Sprache eines Users kommt aus seiner Einstellung oder dem Slack-Profil (siehe
+// slack/user.Locale), Texte an Kanäle verwenden Default.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	German  = "de"
+	English = "en"
+)
+
+// Catalog ordnet Schlüsseln Texte zu, Platzhalter folgen fmt.Sprintf.
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	German:  de,
+	English: en,
+}
+
+// Supported liefert die Sprachen, für die es einen Catalog gibt.
+func Supported() []string {
+	return []string{German, English}
+}
+
+// Normalize macht aus Slack-Locales wie "en-US" oder Accept-Language-Werten wie "en_GB" die
+// Sprache des Catalogs. Nicht unterstützte Sprachen ergeben "".
+func Normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+	if _, ok := catalogs[locale]; ok {
+		return locale
+	}
+	return ""
+}
+
+// Default ist die Sprache aus BOT_LOCALE, sonst Deutsch.
+func Default() string {
+	if locale := Normalize(os.Getenv("BOT_LOCALE")); locale != "" {
+		return locale
+	}
+	return German
+}
+
+// T liefert den Text zum Schlüssel in der Sprache. Fehlt er dort, gilt Default und dann
+// Deutsch; unbekannte Schlüssel werden unverändert zurückgegeben.
+func T(locale string, key string, args ...interface{}) string {
+	for _, candidate := range []string{Normalize(locale), Default(), German} {
+		if text, ok := catalogs[candidate][key]; ok {
+			if len(args) == 0 {
+				return text
+			}
+			return fmt.Sprintf(text, args...)
+		}
+	}
+	return key
+}
+
+// Date formatiert einen Tag, z. B. 24.12.2024 oder Dec 24, 2024.
+func Date(locale string, t time.Time) string {
+	return t.Format(T(locale, "format.date"))
+}
+
+// DateTime formatiert Tag und Uhrzeit.
+func DateTime(locale string, t time.Time) string {
+	return t.Format(T(locale, "format.datetime"))
+}
+
+// DayMonth formatiert einen Tag ohne Jahr, z. B. 24.12. oder Dec 24.
+func DayMonth(locale string, t time.Time) string {
+	return t.Format(T(locale, "format.daymonth"))
+}
+
+// Month liefert den Namen des Monats.
+func Month(locale string, m time.Month) string {
+	return T(locale, fmt.Sprintf("month.%d", m))
+}
+
+// FromAcceptLanguage wählt die erste unterstützte Sprache aus dem Accept-Language-Header,
+// ohne passende Sprache gilt Default.
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if locale := Normalize(tag); locale != "" {
+			return locale
+		}
+	}
+	return Default()
+}
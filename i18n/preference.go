@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"go-slack-ics/system"
+	"log"
+	"sync"
+)
+
+func preferenceKey(user string) string {
+	return "i18n:locale:" + user
+}
+
+var (
+	listenersMu sync.Mutex
+	listeners   []func(user string)
+)
+
+// OnPreferenceChange registriert eine Funktion, die nach jeder geänderten Spracheinstellung
+// mit der User-ID aufgerufen wird.
+func OnPreferenceChange(fn func(user string)) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Preference liefert die eingestellte Sprache des Users oder "".
+func Preference(user string) string {
+	if user == "" {
+		return ""
+	}
+	var locale string
+	if err := system.RedisInstance().Get(preferenceKey(user), "", &locale); err != nil {
+		if !errors.Is(err, system.ErrNil) {
+			log.Printf("Fehler beim Lesen der Sprache von %s: %v", user, err)
+		}
+		return ""
+	}
+	return Normalize(locale)
+}
+
+// SetPreference speichert die Sprache des Users, "" löscht die Einstellung.
+func SetPreference(user string, locale string) error {
+	redis := system.RedisInstance()
+	if locale == "" {
+		if err := redis.Del(preferenceKey(user)); err != nil {
+			return err
+		}
+	} else {
+		normalized := Normalize(locale)
+		if normalized == "" {
+			return fmt.Errorf("nicht unterstützte Sprache: %s", locale)
+		}
+		if err := redis.Set(preferenceKey(user), normalized, ""); err != nil {
+			return err
+		}
+	}
+
+	listenersMu.Lock()
+	fns := listeners
+	listenersMu.Unlock()
+	for _, fn := range fns {
+		fn(user)
+	}
+	return nil
+}
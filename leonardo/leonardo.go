@@ -1,6 +1,7 @@
 package leonardo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -45,9 +46,9 @@ func (leonardo *Leonardo) ExtractFileNameFromURL(fileURL string) (string, error)
 	return fileName, nil
 }
 
-func (leonardo *Leonardo) LoadImage(url string) ([]byte, string, error) {
+func (leonardo *Leonardo) LoadImage(ctx context.Context, url string) ([]byte, string, error) {
 	// Erstelle eine neue HTTP-Anfrage
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %v", err)
 	}
@@ -81,18 +82,27 @@ func (leonardo *Leonardo) LoadImage(url string) ([]byte, string, error) {
 	return imageData, filename, nil
 }
 
-func (leonardo *Leonardo) GetImage(id string) ([]byte, string, error) {
+func (leonardo *Leonardo) GetImage(ctx context.Context, id string) ([]byte, string, error) {
 	generateUrl := "https://cloud.leonardo.ai/api/rest/v1/generations/" + id
 
-	req, _ := http.NewRequest("GET", generateUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", generateUrl, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
+	}
 
 	req.Header.Add("accept", "application/json")
 	req.Header.Add("authorization", "Bearer "+leonardo.apiKey)
 
-	res, _ := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to execute request: %v", err)
+	}
 
 	defer res.Body.Close()
 	jsonData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response body: %v", err)
+	}
 
 	// Erstelle eine Instanz von Payload
 	var responseData ImageResponse
@@ -105,12 +115,22 @@ func (leonardo *Leonardo) GetImage(id string) ([]byte, string, error) {
 	}
 
 	log.Println("Get ID:", responseData.GenerationsByPK.Status)
+	if responseData.GenerationsByPK.Status == "FAILED" {
+		return nil, "", fmt.Errorf("generation %s failed", id)
+	}
 	if responseData.GenerationsByPK.Status != "COMPLETE" {
-		time.Sleep(5 * time.Second)
-		return leonardo.GetImage(id)
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+		return leonardo.GetImage(ctx, id)
+	}
+	if len(responseData.GenerationsByPK.GeneratedImages) == 0 {
+		return nil, "", fmt.Errorf("generation %s has no images", id)
 	}
 
-	return leonardo.LoadImage(responseData.GenerationsByPK.GeneratedImages[0].URL)
+	return leonardo.LoadImage(ctx, responseData.GenerationsByPK.GeneratedImages[0].URL)
 }
 
 type GeneratedImage struct {
@@ -132,7 +152,7 @@ type ImageResponse struct {
 	GenerationsByPK GenerationsByPK `json:"generations_by_pk"`
 }
 
-func (leonardo *Leonardo) Generate(prompt string) ([]byte, string, error) {
+func (leonardo *Leonardo) Generate(ctx context.Context, prompt string) ([]byte, string, error) {
 	// Definiere den String als Map
 	data := map[string]interface{}{
 		"alchemy":     true,
@@ -154,12 +174,18 @@ func (leonardo *Leonardo) Generate(prompt string) ([]byte, string, error) {
 	// Erstelle den Payload
 	payload := strings.NewReader(string(jsonData))
 
-	req, _ := http.NewRequest("POST", leonardo.Endpoint, payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", leonardo.Endpoint, payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
+	}
 	req.Header.Add("accept", "application/json")
 	req.Header.Add("content-type", "application/json")
 	req.Header.Add("authorization", "Bearer "+leonardo.apiKey)
 
-	res, _ := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to execute request: %v", err)
+	}
 
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -168,6 +194,12 @@ func (leonardo *Leonardo) Generate(prompt string) ([]byte, string, error) {
 		}
 	}(res.Body)
 	jsonData, err = io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response body: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to start generation, status code: %d", res.StatusCode)
+	}
 
 	// Erstelle eine Instanz von Payload
 	var responseData Payload
@@ -181,13 +213,13 @@ func (leonardo *Leonardo) Generate(prompt string) ([]byte, string, error) {
 
 	log.Println("Generation ID:", responseData.SDGenerationJob.GenerationId)
 
-	return leonardo.GetImage(responseData.SDGenerationJob.GenerationId)
+	return leonardo.GetImage(ctx, responseData.SDGenerationJob.GenerationId)
 }
 
-func NewTextToImage(prompt string) ([]byte, string, error) {
+func NewTextToImage(ctx context.Context, prompt string) ([]byte, string, error) {
 	leonardo := Leonardo{}
 	leonardo.Endpoint = "https://cloud.leonardo.ai/api/rest/v1/generations"
 	leonardo.apiKey = os.Getenv("LEONARDO_API_KEY")
 
-	return leonardo.Generate(prompt)
+	return leonardo.Generate(ctx, prompt)
 }
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/joho/godotenv"
 	"go-slack-ics/calendar"
 	slackUser "go-slack-ics/slack/user"
 	"go-slack-ics/web"
 	"log"
+	"os"
 	"time"
 )
 
@@ -45,16 +47,67 @@ func startTwelveHourlyTicker() {
 	}
 }
 
+// preview rendert die Kalender-Templates auf der Konsole: go run . preview [quelle] [YYYY-MM-DD]
+func preview(args []string) {
+	name := calendar.Sources[0].Name
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	date := time.Now()
+	if len(args) > 1 {
+		var err error
+		date, err = time.ParseInLocation("2006-01-02", args[1], time.Local)
+		if err != nil {
+			log.Fatalf("Ungültiges Datum %s: %v", args[1], err)
+		}
+	}
+
+	if err := calendar.Preview(os.Stdout, name, date); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// usersSyncInterval liest SLACK_USERS_SYNC (z. B. "6h"), Standard sind sechs Stunden.
+func usersSyncInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv("SLACK_USERS_SYNC")); err == nil && interval > 0 {
+		return interval
+	}
+	return 6 * time.Hour
+}
+
 func main() {
 	err := godotenv.Load(".env")
-	slackUser.InitUsers()
-
 	if err != nil {
 		log.Printf("Error loading .env file")
 	}
 
+	if err := calendar.ValidateTemplates(); err != nil {
+		log.Fatalf("Kalender-Templates sind ungültig: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "preview" {
+		preview(os.Args[2:])
+		return
+	}
+
+	if calendar.Delivery() == calendar.DeliveryScheduled {
+		calendar.EnableScheduledDelivery()
+	}
+	if err := calendar.DefaultStore.Reload(); err != nil {
+		log.Printf("Fehler beim Laden der Kalender: %v", err)
+	}
+	if err := calendar.DefaultStore.Watch(); err != nil {
+		log.Printf("Kalender werden nicht auf Änderungen beobachtet: %v", err)
+	}
+
+	if err := slackUser.DefaultDirectory.Load(); err != nil {
+		log.Printf("Slack-User noch nicht im Cache: %v", err)
+	}
+	go slackUser.DefaultDirectory.Run(context.Background(), usersSyncInterval())
+
 	go func() {
-		fmt.Printf("Start Slack Notification for Users: %s \n", slackUser.Users)
+		fmt.Printf("Start Slack Notification for Users: %s, %s \n", slackUser.Mention("Wolf"), slackUser.Mention("Frank"))
 		startTwelveHourlyTicker()
 	}()
 
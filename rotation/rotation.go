@@ -0,0 +1,88 @@
+// Package rotation verteilt den Wochendienst für die Mülltonnen. Die Wochen wechseln reihum
+// zwischen den Personen in Order, einzelne Wochen lassen sich mit der nächsten Person tauschen.
+package rotation
+
+import (
+	"fmt"
+	slackUser "go-slack-ics/slack/user"
+	"go-slack-ics/system"
+	"log"
+	"time"
+)
+
+// Order ist die Reihenfolge des Dienstes, die Namen löst slackUser.Resolve auf.
+var Order = []string{"Wolf", "Frank"}
+
+// epoch ist ein Montag, die Woche ab epoch gehört Order[0].
+var epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// swapTTL hält getauschte Wochen lange genug vor, auch für Tausche weit im Voraus.
+const swapTTL = 120 * 24 * time.Hour
+
+// day legt das Datum auf Mitternacht UTC, so wie gocal ganztägige Termine liefert.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func weekIndex(t time.Time) int {
+	days := int(day(t).Sub(epoch).Hours() / 24)
+	if days < 0 {
+		days -= 6
+	}
+	return days / 7
+}
+
+// WeekStart liefert den Montag der Woche von t.
+func WeekStart(t time.Time) time.Time {
+	return epoch.AddDate(0, 0, weekIndex(t)*7)
+}
+
+func swapKey(t time.Time) string {
+	year, week := day(t).ISOWeek()
+	return fmt.Sprintf("rotation:swap:%d-W%02d", year, week)
+}
+
+// OnDuty liefert die Slack-User-ID der Person, die in der Woche von t Dienst hat.
+func OnDuty(t time.Time) string {
+	i := weekIndex(t)
+	if IsSwapped(t) {
+		i++
+	}
+	n := len(Order)
+	return slackUser.Resolve(Order[(i%n+n)%n])
+}
+
+// Position liefert die Stelle (ab 1) des Users in der Reihenfolge.
+func Position(userID string) (int, bool) {
+	for i, name := range Order {
+		if slackUser.Resolve(name) == userID {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func IsSwapped(t time.Time) bool {
+	swapped, err := system.RedisInstance().Exists(swapKey(t))
+	if err != nil {
+		log.Printf("Fehler beim Lesen des Tauschs für %s: %v", swapKey(t), err)
+	}
+	return swapped
+}
+
+// Swap gibt den Dienst der Woche von t an die nächste Person in der Reihenfolge ab. Ein zweiter
+// Aufruf für dieselbe Woche macht den Tausch rückgängig. Das Ergebnis meldet, ob die Woche
+// danach getauscht ist.
+func Swap(t time.Time) (bool, error) {
+	redis := system.RedisInstance()
+	if IsSwapped(t) {
+		if err := redis.Del(swapKey(t)); err != nil {
+			return true, err
+		}
+		return false, nil
+	}
+	if _, err := redis.SetNX(swapKey(t), 1, swapTTL); err != nil {
+		return false, err
+	}
+	return true, nil
+}
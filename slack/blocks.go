@@ -0,0 +1,229 @@
+package slack
+
+import "encoding/json"
+
+// Block ist ein Block-Kit-Block. Welche Felder gesetzt sind, hängt vom Typ ab:
+// section (Text, Fields, Accessory), header (Text), divider, context und actions (Elements),
+// image (ImageURL, AltText, Title), input (Label, Element, Hint) und rich_text (Elements).
+type Block struct {
+	Type           string   `json:"type"`
+	BlockID        string   `json:"block_id,omitempty"`
+	Text           *Text    `json:"text,omitempty"`
+	Fields         []Text   `json:"fields,omitempty"`
+	Elements       Elements `json:"elements,omitempty"`
+	Accessory      Element  `json:"accessory,omitempty"`
+	ImageURL       string   `json:"image_url,omitempty"`
+	AltText        string   `json:"alt_text,omitempty"`
+	Title          *Text    `json:"title,omitempty"`
+	Label          *Text    `json:"label,omitempty"`
+	Element        Element  `json:"element,omitempty"`
+	Hint           *Text    `json:"hint,omitempty"`
+	Optional       bool     `json:"optional,omitempty"`
+	DispatchAction bool     `json:"dispatch_action,omitempty"`
+}
+
+// UnmarshalJSON dekodiert die Felder accessory und element anhand ihres Typs.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	type plain Block
+	var raw struct {
+		plain
+		Accessory json.RawMessage `json:"accessory,omitempty"`
+		Element   json.RawMessage `json:"element,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*b = Block(raw.plain)
+	b.Accessory = decodeElement(raw.Accessory)
+	b.Element = decodeElement(raw.Element)
+	return nil
+}
+
+// Element ist ein Block-Element, z. B. ein Button, ein Bild oder ein Textobjekt im context-Block.
+type Element interface {
+	ElementType() string
+}
+
+// Elements ist eine Liste von Elementen, die beim Dekodieren den passenden Typ erhält.
+type Elements []Element
+
+func (e *Elements) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	elements := make(Elements, 0, len(raw))
+	for _, r := range raw {
+		if element := decodeElement(r); element != nil {
+			elements = append(elements, element)
+		}
+	}
+	*e = elements
+	return nil
+}
+
+// decodeElement wählt den Go-Typ anhand von "type". Unbekannte oder fehlerhafte Elemente
+// bleiben als RawElement erhalten, damit eingehende Events nie am Dekodieren scheitern.
+func decodeElement(data json.RawMessage) Element {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return &RawElement{Raw: data}
+	}
+
+	var element Element
+	switch head.Type {
+	case "plain_text", "mrkdwn":
+		element = &Text{}
+	case "button":
+		element = &Button{}
+	case "image":
+		element = &ImageElement{}
+	case "plain_text_input":
+		element = &PlainTextInput{}
+	case "static_select":
+		element = &StaticSelect{}
+	case "datepicker":
+		element = &DatePicker{}
+	case "rich_text_section", "rich_text_preformatted", "rich_text_quote", "rich_text_list":
+		element = &RichTextSection{}
+	case "text", "link", "user", "channel", "usergroup", "emoji", "broadcast":
+		element = &RichTextElement{}
+	default:
+		return &RawElement{Type: head.Type, Raw: data}
+	}
+
+	if err := json.Unmarshal(data, element); err != nil {
+		return &RawElement{Type: head.Type, Raw: data}
+	}
+	return element
+}
+
+type Text struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Emoji    bool   `json:"emoji,omitempty"`
+	Verbatim bool   `json:"verbatim,omitempty"`
+}
+
+func (t Text) ElementType() string { return t.Type }
+
+// Confirm ist der Bestätigungsdialog eines interaktiven Elements.
+type Confirm struct {
+	Title   *Text  `json:"title"`
+	Text    *Text  `json:"text"`
+	Confirm *Text  `json:"confirm"`
+	Deny    *Text  `json:"deny"`
+	Style   string `json:"style,omitempty"`
+}
+
+type Button struct {
+	Type               string   `json:"type"`
+	Text               *Text    `json:"text"`
+	ActionID           string   `json:"action_id,omitempty"`
+	Value              string   `json:"value,omitempty"`
+	URL                string   `json:"url,omitempty"`
+	Style              string   `json:"style,omitempty"`
+	Confirm            *Confirm `json:"confirm,omitempty"`
+	AccessibilityLabel string   `json:"accessibility_label,omitempty"`
+}
+
+func (b *Button) ElementType() string { return "button" }
+
+type ImageElement struct {
+	Type     string `json:"type"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+func (i *ImageElement) ElementType() string { return "image" }
+
+type PlainTextInput struct {
+	Type         string `json:"type"`
+	ActionID     string `json:"action_id,omitempty"`
+	Placeholder  *Text  `json:"placeholder,omitempty"`
+	InitialValue string `json:"initial_value,omitempty"`
+	Multiline    bool   `json:"multiline,omitempty"`
+	MaxLength    int    `json:"max_length,omitempty"`
+}
+
+func (p *PlainTextInput) ElementType() string { return "plain_text_input" }
+
+type Option struct {
+	Text        *Text  `json:"text"`
+	Value       string `json:"value"`
+	Description *Text  `json:"description,omitempty"`
+}
+
+type StaticSelect struct {
+	Type          string   `json:"type"`
+	ActionID      string   `json:"action_id,omitempty"`
+	Placeholder   *Text    `json:"placeholder,omitempty"`
+	Options       []Option `json:"options"`
+	InitialOption *Option  `json:"initial_option,omitempty"`
+	Confirm       *Confirm `json:"confirm,omitempty"`
+}
+
+func (s *StaticSelect) ElementType() string { return "static_select" }
+
+type DatePicker struct {
+	Type        string   `json:"type"`
+	ActionID    string   `json:"action_id,omitempty"`
+	InitialDate string   `json:"initial_date,omitempty"`
+	Placeholder *Text    `json:"placeholder,omitempty"`
+	Confirm     *Confirm `json:"confirm,omitempty"`
+}
+
+func (d *DatePicker) ElementType() string { return "datepicker" }
+
+// RichTextSection ist ein Container im rich_text-Block: Absatz, Codeblock, Zitat oder Liste.
+type RichTextSection struct {
+	Type     string   `json:"type"`
+	Elements Elements `json:"elements"`
+	Style    string   `json:"style,omitempty"`
+	Indent   int      `json:"indent,omitempty"`
+	Border   int      `json:"border,omitempty"`
+}
+
+func (r *RichTextSection) ElementType() string { return r.Type }
+
+type RichTextStyle struct {
+	Bold   bool `json:"bold,omitempty"`
+	Italic bool `json:"italic,omitempty"`
+	Strike bool `json:"strike,omitempty"`
+	Code   bool `json:"code,omitempty"`
+}
+
+// RichTextElement ist ein Inline-Element im rich_text-Block (Text, Link, Erwähnung, Emoji).
+type RichTextElement struct {
+	Type        string         `json:"type"`
+	Text        string         `json:"text,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	UserID      string         `json:"user_id,omitempty"`
+	ChannelID   string         `json:"channel_id,omitempty"`
+	UsergroupID string         `json:"usergroup_id,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Range       string         `json:"range,omitempty"`
+	Style       *RichTextStyle `json:"style,omitempty"`
+}
+
+func (r *RichTextElement) ElementType() string { return r.Type }
+
+// RawElement hält ein Element, für das es keinen eigenen Typ gibt, unverändert fest.
+type RawElement struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+func (r *RawElement) ElementType() string { return r.Type }
+
+func (r *RawElement) MarshalJSON() ([]byte, error) {
+	return r.Raw, nil
+}
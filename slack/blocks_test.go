@@ -0,0 +1,185 @@
+package slack
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTrip kodiert blocks und liest sie wieder ein.
+func roundTrip(t *testing.T, blocks []Block) []Block {
+	t.Helper()
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Block
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	return decoded
+}
+
+func TestBlocksRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		block Block
+	}{
+		{"text im context", NewBlocks().Context(Mrkdwn("*fett*"), PlainText("klar")).Blocks()[0]},
+		{"fields", NewBlocks().Fields("*Papier*", "morgen").Blocks()[0]},
+		{"button als accessory", NewBlocks().SectionWithAccessory("Abfuhr", NewButton("ack", "Erledigt", "uid").Primary().WithConfirm("Sicher?", "Wirklich?", "Ja", "Nein")).Blocks()[0]},
+		{"link-button", NewBlocks().Actions(NewButton("open", "Öffnen", "").WithURL("https://example.org")).Blocks()[0]},
+		{"bild als accessory", NewBlocks().SectionWithAccessory("Bild", NewImage("https://example.org/a.png", "A")).Blocks()[0]},
+		{"bild im context", NewBlocks().Context(NewImage("https://example.org/a.png", "A"), Mrkdwn("Text")).Blocks()[0]},
+		{"input mit element", NewBlocks().Input("Prompt", NewPlainTextInput("prompt", "Beschreibung")).BlockID("prompt").Blocks()[0]},
+		{"static select", NewBlocks().Actions(NewStaticSelect("year", "Jahr", NewOption("2024", "2024"), NewOption("2025", "2025"))).Blocks()[0]},
+		{"datepicker als element", NewBlocks().Input("Datum", NewDatePicker("date", "2024-05-01")).Blocks()[0]},
+		{"rich text", NewBlocks().RichText(
+			&RichTextSection{Type: "rich_text_section", Elements: Elements{
+				&RichTextElement{Type: "text", Text: "Hallo ", Style: &RichTextStyle{Bold: true}},
+				&RichTextElement{Type: "user", UserID: "U123"},
+				&RichTextElement{Type: "link", URL: "https://example.org", Text: "Link"},
+				&RichTextElement{Type: "emoji", Name: "wastebasket"},
+			}},
+			&RichTextSection{Type: "rich_text_list", Style: "bullet", Indent: 1, Elements: Elements{
+				&RichTextSection{Type: "rich_text_section", Elements: Elements{&RichTextElement{Type: "text", Text: "Punkt"}}},
+			}},
+			&RichTextSection{Type: "rich_text_preformatted", Border: 1, Elements: Elements{&RichTextElement{Type: "text", Text: "code"}}},
+		).Blocks()[0]},
+		{"unbekanntes element", NewBlocks().Actions(&RawElement{Type: "overflow", Raw: json.RawMessage(`{"type":"overflow","action_id":"more","options":[{"text":{"type":"plain_text","text":"A"},"value":"a"}]}`)}).Blocks()[0]},
+		{"unbekanntes accessory", Block{Type: "section", Text: Mrkdwn("x"), Accessory: &RawElement{Type: "checkboxes", Raw: json.RawMessage(`{"type":"checkboxes","action_id":"c"}`)}}},
+		{"unbekanntes input-element", Block{Type: "input", Label: PlainText("Zeit"), Element: &RawElement{Type: "timepicker", Raw: json.RawMessage(`{"type":"timepicker","action_id":"t"}`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundTrip(t, []Block{tt.block})
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.block) {
+				want, _ := json.Marshal(tt.block)
+				have, _ := json.Marshal(got)
+				t.Fatalf("nach dem Round-Trip:\n%s\nerwartet:\n%s", have, want)
+			}
+		})
+	}
+}
+
+func TestDecodeElementFallback(t *testing.T) {
+	tests := []struct {
+		name, data string
+		typ        string
+	}{
+		{"ohne type", `{"action_id":"x"}`, ""},
+		{"falsches feld", `{"type":"button","text":42}`, "button"},
+		{"kein objekt", `"text"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			element := decodeElement(json.RawMessage(tt.data))
+			raw, ok := element.(*RawElement)
+			if !ok {
+				t.Fatalf("%T, erwartet *RawElement", element)
+			}
+			if raw.Type != tt.typ || string(raw.Raw) != tt.data {
+				t.Fatalf("RawElement{%q, %s}", raw.Type, raw.Raw)
+			}
+			data, _ := json.Marshal(raw)
+			if string(data) != tt.data {
+				t.Fatalf("kodiert als %s", data)
+			}
+		})
+	}
+	if decodeElement(nil) != nil || decodeElement(json.RawMessage("null")) != nil {
+		t.Fatal("leeres Element liefert nicht nil")
+	}
+}
+
+func TestValidateBlocks(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("ä", n) }
+	texts := func(n int) []Element {
+		elements := make([]Element, n)
+		for i := range elements {
+			elements[i] = Mrkdwn("x")
+		}
+		return elements
+	}
+	buttons := func(n int) []Element {
+		elements := make([]Element, n)
+		for i := range elements {
+			elements[i] = NewButton("b", "B", "")
+		}
+		return elements
+	}
+	confirm := func(title, text, ok, deny string) Element {
+		return NewButton("b", "B", "").WithConfirm(title, text, ok, deny)
+	}
+
+	tests := []struct {
+		name    string
+		builder *BlockBuilder
+		wantErr string
+	}{
+		{"header 150", NewBlocks().Header(long(150)), ""},
+		{"header 151", NewBlocks().Header(long(151)), "text hat 151 Zeichen"},
+		{"header mrkdwn", NewBlocks().Add(Block{Type: "header", Text: Mrkdwn("x")}), "plain_text"},
+		{"section 3001", NewBlocks().Section(long(3001)), "text hat 3001"},
+		{"section leer", NewBlocks().Add(Block{Type: "section"}), "text oder fields"},
+		{"10 fields", NewBlocks().Fields(strings.Split(strings.Repeat("x,", 9)+"x", ",")...), ""},
+		{"11 fields", NewBlocks().Fields(strings.Split(strings.Repeat("x,", 10)+"x", ",")...), "11 fields"},
+		{"field 2001", NewBlocks().Fields(long(2001)), "field hat 2001"},
+		{"context leer", NewBlocks().Context(), "0 elements"},
+		{"context 10", NewBlocks().Context(texts(10)...), ""},
+		{"context 11", NewBlocks().Context(texts(11)...), "11 elements"},
+		{"context text 3001", NewBlocks().Context(Mrkdwn(long(3001))), "text hat 3001"},
+		{"context text als wert 3001", NewBlocks().Context(Text{Type: "mrkdwn", Text: long(3001)}), "text hat 3001"},
+		{"context text als wert", NewBlocks().Context(Text{Type: "plain_text", Text: long(3000)}), ""},
+		{"context text nil", NewBlocks().Context((*Text)(nil)), "text fehlt"},
+		{"context bild ohne alt", NewBlocks().Context(NewImage("https://example.org/a.png", "")), "alt_text"},
+		{"actions leer", NewBlocks().Actions(), "0 elements"},
+		{"actions 25", NewBlocks().Actions(buttons(25)...), ""},
+		{"actions 26", NewBlocks().Actions(buttons(26)...), "26 elements"},
+		{"button text 76", NewBlocks().Actions(NewButton("b", long(76), "")), "button text hat 76"},
+		{"button style", NewBlocks().Actions(&Button{Type: "button", Text: PlainText("B"), Style: "rot"}), "style"},
+		{"button ohne text", NewBlocks().Actions(&Button{Type: "button"}), "braucht einen text"},
+		{"action_id 256", NewBlocks().Actions(NewButton(long(256), "B", "")), "action_id"},
+		{"accessory geprüft", NewBlocks().SectionWithAccessory("x", NewButton("b", long(76), "")), "button text"},
+		{"confirm ok", NewBlocks().Actions(confirm(long(100), long(300), long(30), long(30))), ""},
+		{"confirm title 101", NewBlocks().Actions(confirm(long(101), "t", "ja", "nein")), "confirm title hat 101"},
+		{"confirm text 301", NewBlocks().Actions(confirm("t", long(301), "ja", "nein")), "confirm text hat 301"},
+		{"confirm button 31", NewBlocks().Actions(confirm("t", "t", long(31), "nein")), "confirm button hat 31"},
+		{"deny button 31", NewBlocks().Actions(confirm("t", "t", "ja", long(31))), "deny button hat 31"},
+		{"confirm unvollständig", NewBlocks().Actions(&Button{Type: "button", Text: PlainText("B"), Confirm: &Confirm{Title: PlainText("t")}}), "confirm braucht"},
+		{"select ohne options", NewBlocks().Actions(NewStaticSelect("s", "Wahl")), "0 options"},
+		{"select confirm", NewBlocks().Actions(&StaticSelect{Type: "static_select", Options: []Option{NewOption("a", "a")}, Confirm: &Confirm{}}), "confirm braucht"},
+		{"image ohne url", NewBlocks().Image("", "alt", ""), "image_url"},
+		{"input ohne element", NewBlocks().Add(Block{Type: "input", Label: PlainText("x")}), "label und element"},
+		{"block_id 256", NewBlocks().Divider().BlockID(long(256)), "block_id hat 256"},
+		{"unbekanntes element", NewBlocks().Actions(&RawElement{Type: "overflow", Raw: json.RawMessage(`{}`)}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unerwarteter Fehler: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Fehler %v, erwartet %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBlocksCount(t *testing.T) {
+	builder := NewBlocks()
+	for i := 0; i < MaxMessageBlocks+1; i++ {
+		builder.Divider()
+	}
+	if _, err := builder.Build(); err == nil {
+		t.Fatalf("%d Blöcke in einer Nachricht akzeptiert", MaxMessageBlocks+1)
+	}
+	if err := ValidateBlocks(builder.Blocks(), MaxViewBlocks); err != nil {
+		t.Fatalf("%d Blöcke in einer View abgelehnt: %v", MaxMessageBlocks+1, err)
+	}
+}
@@ -0,0 +1,333 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits der Block-Kit-Referenz, siehe https://api.slack.com/reference/block-kit
+const (
+	MaxMessageBlocks = 50
+	MaxViewBlocks    = 100
+	maxTextLength    = 3000
+	maxHeaderLength  = 150
+	maxFieldLength   = 2000
+	maxFields        = 10
+	maxContextItems  = 10
+	maxActionItems   = 25
+	maxButtonText    = 75
+	maxIDLength      = 255
+)
+
+// BlockBuilder baut eine Liste von Blöcken auf:
+//
+//	blocks, err := slack.NewBlocks().
+//		Header("Abfuhr morgen").
+//		Section("*Biotonne* an die Straße stellen").
+//		Actions(slack.NewButton("ack", "Erledigt", "uid").Primary()).
+//		Build()
+type BlockBuilder struct {
+	blocks []Block
+}
+
+func NewBlocks() *BlockBuilder {
+	return &BlockBuilder{}
+}
+
+func PlainText(text string) *Text {
+	return &Text{Type: "plain_text", Text: text, Emoji: true}
+}
+
+func Mrkdwn(text string) *Text {
+	return &Text{Type: "mrkdwn", Text: text}
+}
+
+func (b *BlockBuilder) Add(blocks ...Block) *BlockBuilder {
+	b.blocks = append(b.blocks, blocks...)
+	return b
+}
+
+func (b *BlockBuilder) Header(text string) *BlockBuilder {
+	return b.Add(Block{Type: "header", Text: PlainText(text)})
+}
+
+func (b *BlockBuilder) Section(text string) *BlockBuilder {
+	return b.Add(Block{Type: "section", Text: Mrkdwn(text)})
+}
+
+// SectionWithAccessory hängt ein Element (z. B. Button oder Bild) rechts an die Section.
+func (b *BlockBuilder) SectionWithAccessory(text string, accessory Element) *BlockBuilder {
+	return b.Add(Block{Type: "section", Text: Mrkdwn(text), Accessory: accessory})
+}
+
+// Fields erzeugt eine Section mit zweispaltig dargestellten mrkdwn-Feldern.
+func (b *BlockBuilder) Fields(fields ...string) *BlockBuilder {
+	block := Block{Type: "section"}
+	for _, field := range fields {
+		block.Fields = append(block.Fields, *Mrkdwn(field))
+	}
+	return b.Add(block)
+}
+
+func (b *BlockBuilder) Divider() *BlockBuilder {
+	return b.Add(Block{Type: "divider"})
+}
+
+// Context erwartet Textobjekte oder Bilder.
+func (b *BlockBuilder) Context(elements ...Element) *BlockBuilder {
+	return b.Add(Block{Type: "context", Elements: elements})
+}
+
+// Actions erwartet interaktive Elemente wie Buttons oder Selects.
+func (b *BlockBuilder) Actions(elements ...Element) *BlockBuilder {
+	return b.Add(Block{Type: "actions", Elements: elements})
+}
+
+func (b *BlockBuilder) Image(url, altText, title string) *BlockBuilder {
+	block := Block{Type: "image", ImageURL: url, AltText: altText}
+	if title != "" {
+		block.Title = PlainText(title)
+	}
+	return b.Add(block)
+}
+
+func (b *BlockBuilder) Input(label string, element Element) *BlockBuilder {
+	return b.Add(Block{Type: "input", Label: PlainText(label), Element: element})
+}
+
+// RichText erwartet RichTextSection-Container.
+func (b *BlockBuilder) RichText(elements ...Element) *BlockBuilder {
+	return b.Add(Block{Type: "rich_text", Elements: elements})
+}
+
+// BlockID setzt die block_id des zuletzt hinzugefügten Blocks.
+func (b *BlockBuilder) BlockID(id string) *BlockBuilder {
+	if len(b.blocks) > 0 {
+		b.blocks[len(b.blocks)-1].BlockID = id
+	}
+	return b
+}
+
+// Blocks liefert die Blöcke ohne Prüfung.
+func (b *BlockBuilder) Blocks() []Block {
+	return b.blocks
+}
+
+// Build prüft die Blöcke gegen die Limits einer Nachricht.
+func (b *BlockBuilder) Build() ([]Block, error) {
+	return b.blocks, ValidateBlocks(b.blocks, MaxMessageBlocks)
+}
+
+func NewButton(actionID, text, value string) *Button {
+	return &Button{Type: "button", Text: PlainText(text), ActionID: actionID, Value: value}
+}
+
+func (b *Button) Primary() *Button {
+	b.Style = "primary"
+	return b
+}
+
+func (b *Button) Danger() *Button {
+	b.Style = "danger"
+	return b
+}
+
+func (b *Button) WithURL(url string) *Button {
+	b.URL = url
+	return b
+}
+
+// WithConfirm lässt Slack vor dem Auslösen einen Bestätigungsdialog anzeigen.
+func (b *Button) WithConfirm(title, text, confirm, deny string) *Button {
+	b.Confirm = NewConfirm(title, text, confirm, deny)
+	return b
+}
+
+func NewConfirm(title, text, confirm, deny string) *Confirm {
+	return &Confirm{
+		Title:   PlainText(title),
+		Text:    Mrkdwn(text),
+		Confirm: PlainText(confirm),
+		Deny:    PlainText(deny),
+	}
+}
+
+func NewImage(url, altText string) *ImageElement {
+	return &ImageElement{Type: "image", ImageURL: url, AltText: altText}
+}
+
+func NewPlainTextInput(actionID, placeholder string) *PlainTextInput {
+	input := &PlainTextInput{Type: "plain_text_input", ActionID: actionID}
+	if placeholder != "" {
+		input.Placeholder = PlainText(placeholder)
+	}
+	return input
+}
+
+func NewOption(text, value string) Option {
+	return Option{Text: PlainText(text), Value: value}
+}
+
+func NewStaticSelect(actionID, placeholder string, options ...Option) *StaticSelect {
+	return &StaticSelect{Type: "static_select", ActionID: actionID, Placeholder: PlainText(placeholder), Options: options}
+}
+
+func NewDatePicker(actionID, initialDate string) *DatePicker {
+	return &DatePicker{Type: "datepicker", ActionID: actionID, InitialDate: initialDate}
+}
+
+// ValidateBlocks prüft die Blöcke gegen die Limits von Slack. Für Nachrichten ist maxBlocks
+// MaxMessageBlocks, für Modals und den App-Home MaxViewBlocks.
+func ValidateBlocks(blocks []Block, maxBlocks int) error {
+	if len(blocks) > maxBlocks {
+		return fmt.Errorf("%d Blöcke, erlaubt sind %d", len(blocks), maxBlocks)
+	}
+
+	for i, block := range blocks {
+		if err := block.validate(); err != nil {
+			return fmt.Errorf("Block %d (%s): %w", i, block.Type, err)
+		}
+	}
+	return nil
+}
+
+func (b Block) validate() error {
+	if err := checkLength("block_id", b.BlockID, maxIDLength); err != nil {
+		return err
+	}
+
+	switch b.Type {
+	case "header":
+		if b.Text == nil || b.Text.Type != "plain_text" {
+			return errors.New("header braucht einen plain_text")
+		}
+		return checkLength("text", b.Text.Text, maxHeaderLength)
+	case "section":
+		if b.Text == nil && len(b.Fields) == 0 {
+			return errors.New("section braucht text oder fields")
+		}
+		if b.Text != nil {
+			if err := checkLength("text", b.Text.Text, maxTextLength); err != nil {
+				return err
+			}
+		}
+		if len(b.Fields) > maxFields {
+			return fmt.Errorf("%d fields, erlaubt sind %d", len(b.Fields), maxFields)
+		}
+		for _, field := range b.Fields {
+			if err := checkLength("field", field.Text, maxFieldLength); err != nil {
+				return err
+			}
+		}
+		if b.Accessory != nil {
+			return validateElement(b.Accessory)
+		}
+	case "context":
+		if len(b.Elements) == 0 || len(b.Elements) > maxContextItems {
+			return fmt.Errorf("%d elements, erlaubt sind 1 bis %d", len(b.Elements), maxContextItems)
+		}
+		for _, element := range b.Elements {
+			if err := validateElement(element); err != nil {
+				return err
+			}
+		}
+	case "actions":
+		if len(b.Elements) == 0 || len(b.Elements) > maxActionItems {
+			return fmt.Errorf("%d elements, erlaubt sind 1 bis %d", len(b.Elements), maxActionItems)
+		}
+		for _, element := range b.Elements {
+			if err := validateElement(element); err != nil {
+				return err
+			}
+		}
+	case "image":
+		if b.ImageURL == "" || b.AltText == "" {
+			return errors.New("image braucht image_url und alt_text")
+		}
+		if err := checkLength("image_url", b.ImageURL, maxTextLength); err != nil {
+			return err
+		}
+		return checkLength("alt_text", b.AltText, maxFieldLength)
+	case "input":
+		if b.Label == nil || b.Element == nil {
+			return errors.New("input braucht label und element")
+		}
+		if err := checkLength("label", b.Label.Text, maxFieldLength); err != nil {
+			return err
+		}
+		return validateElement(b.Element)
+	}
+	return nil
+}
+
+// validateElement prüft ein Element. Text implementiert Element auch als Wert, alle anderen
+// Elemente nur als Pointer.
+func validateElement(element Element) error {
+	switch e := element.(type) {
+	case Text:
+		return checkLength("text", e.Text, maxTextLength)
+	case *Text:
+		if e == nil {
+			return errors.New("text fehlt")
+		}
+		return checkLength("text", e.Text, maxTextLength)
+	case *Button:
+		if e.Text == nil {
+			return errors.New("button braucht einen text")
+		}
+		if e.Style != "" && e.Style != "primary" && e.Style != "danger" {
+			return fmt.Errorf("unbekannter button style %q", e.Style)
+		}
+		for _, check := range []struct {
+			name, value string
+			max         int
+		}{
+			{"button text", e.Text.Text, maxButtonText},
+			{"action_id", e.ActionID, maxIDLength},
+			{"value", e.Value, maxFieldLength},
+			{"url", e.URL, maxTextLength},
+		} {
+			if err := checkLength(check.name, check.value, check.max); err != nil {
+				return err
+			}
+		}
+		return validateConfirm(e.Confirm)
+	case *StaticSelect:
+		if len(e.Options) == 0 || len(e.Options) > 100 {
+			return fmt.Errorf("%d options, erlaubt sind 1 bis 100", len(e.Options))
+		}
+		return validateConfirm(e.Confirm)
+	case *ImageElement:
+		if e.ImageURL == "" || e.AltText == "" {
+			return errors.New("image braucht image_url und alt_text")
+		}
+	}
+	return nil
+}
+
+func validateConfirm(c *Confirm) error {
+	if c == nil {
+		return nil
+	}
+	if c.Title == nil || c.Text == nil || c.Confirm == nil || c.Deny == nil {
+		return errors.New("confirm braucht title, text, confirm und deny")
+	}
+	if err := checkLength("confirm title", c.Title.Text, 100); err != nil {
+		return err
+	}
+	if err := checkLength("confirm text", c.Text.Text, 300); err != nil {
+		return err
+	}
+	if err := checkLength("confirm button", c.Confirm.Text, 30); err != nil {
+		return err
+	}
+	return checkLength("deny button", c.Deny.Text, 30)
+}
+
+func checkLength(name, value string, max int) error {
+	if n := utf8.RuneCountInString(value); n > max {
+		return fmt.Errorf("%s hat %d Zeichen, erlaubt sind %d", name, n, max)
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate-Limit-Stufen der Web API in Aufrufen pro Minute, siehe https://api.slack.com/apis/rate-limits
+const (
+	tier1 = 1
+	tier2 = 20
+	tier3 = 50
+	tier4 = 100
+)
+
+var methodTiers = map[string]int{
+	"apps.connections.open":        tier1,
+	"auth.test":                    tier4,
+	"chat.update":                  tier3,
+	"chat.scheduleMessage":         tier3,
+	"chat.deleteScheduledMessage":  tier3,
+	"files.upload":                 tier2,
+	"files.getUploadURLExternal":   tier4,
+	"files.completeUploadExternal": tier4,
+	"files.info":                   tier4,
+	"oauth.v2.access":              tier4,
+	"users.list":                   tier2,
+	"views.publish":                tier4,
+}
+
+// idempotentMethods dürfen nach 5xx-Antworten und Netzwerkfehlern wiederholt werden. Sie lesen
+// nur oder setzen einen Zustand, der bei einer Wiederholung derselbe bleibt: chat.update schreibt
+// den Text erneut, views.publish die Ansicht, und eine schon gelöschte geplante Nachricht bleibt
+// gelöscht. Bei allen anderen kann Slack den Aufruf schon ausgeführt haben, eine Wiederholung
+// würde z. B. eine Nachricht doppelt posten.
+var idempotentMethods = map[string]bool{
+	"apps.connections.open":       true,
+	"auth.test":                   true,
+	"chat.deleteScheduledMessage": true,
+	"chat.update":                 true,
+	"conversations.info":          true,
+	"files.info":                  true,
+	"users.info":                  true,
+	"users.list":                  true,
+	"views.publish":               true,
+}
+
+// SlackError ist eine Antwort der Slack API mit "ok": false, Code enthält das Feld "error".
+type SlackError struct {
+	Method string
+	Code   string
+}
+
+func (e *SlackError) Error() string {
+	return fmt.Sprintf("slack %s: %s", e.Method, e.Code)
+}
+
+// RateLimitError meldet, dass Slack auch nach allen Wiederholungen mit 429 geantwortet hat.
+type RateLimitError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("slack %s: rate limited, retry after %s", e.Method, e.RetryAfter)
+}
+
+// HTTPError meldet einen unerwarteten HTTP-Status der Slack API.
+type HTTPError struct {
+	Method     string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("slack %s: %s", e.Method, e.Status)
+}
+
+// Request ist ein einzelner Aufruf der Web API.
+type Request struct {
+	Method      string
+	URL         string
+	Channel     string
+	ContentType string
+	Token       string
+	Body        []byte
+}
+
+// Client schickt Anfragen an die Slack API. Er hält die Rate-Limits pro Token und Methode ein, so
+// bremst ein 429 in einem Workspace die anderen nicht. Er wartet bei
+// 429 die Retry-After-Zeit ab und wiederholt den Aufruf, wiederholt 5xx-Antworten und
+// Netzwerkfehler nur für idempotentMethods mit exponentiellem Backoff und verschickt Aufrufe
+// für denselben Kanal in der Reihenfolge, in der sie eingereicht wurden.
+type Client struct {
+	HTTPClient *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+	// Limits überschreibt die Aufrufe pro Minute einzelner Methoden, z. B. in Tests.
+	Limits map[string]int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// queues enthält nur Kanäle mit laufenden oder wartenden Aufrufen
+	queues map[string]*fifo
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		MaxRetries: 3,
+		Backoff:    time.Second,
+		buckets:    make(map[string]*bucket),
+		queues:     make(map[string]*fifo),
+	}
+}
+
+var DefaultClient = NewClient()
+
+// tokenScope kürzt das Token für den Schlüssel der Buckets, damit es nicht im Klartext im
+// Speicher verteilt wird. Aufrufe ohne Token (response_url, oauth.v2.access) teilen sich "".
+func tokenScope(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c *Client) bucket(r Request) *bucket {
+	method := r.Method
+	key := tokenScope(r.Token) + ":" + method
+	perMinute, ok := c.Limits[method]
+	if !ok {
+		perMinute, ok = methodTiers[method]
+	}
+	if !ok {
+		perMinute = tier3
+	}
+	// chat.postMessage erlaubt etwa eine Nachricht pro Sekunde und Kanal
+	if method == "chat.postMessage" {
+		key, perMinute = key+":"+r.Channel, 60
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buckets[key] == nil {
+		c.buckets[key] = newBucket(perMinute)
+	}
+	return c.buckets[key]
+}
+
+// acquire liefert die Warteschlange des Kanals und zählt den Aufruf, der sie benutzt. Jeder
+// acquire braucht ein release.
+func (c *Client) acquire(channel string) *fifo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	q := c.queues[channel]
+	if q == nil {
+		q = &fifo{}
+		c.queues[channel] = q
+	}
+	q.users++
+	return q
+}
+
+// release entfernt die Warteschlange, sobald kein Aufruf sie mehr benutzt. Sonst sammelte der
+// Client über die Zeit eine für jeden Kanal, an den er je geschrieben hat.
+func (c *Client) release(channel string, q *fifo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	q.users--
+	if q.users == 0 {
+		delete(c.queues, channel)
+	}
+}
+
+// Do führt die Anfrage aus und liefert den Body der Antwort.
+func (c *Client) Do(ctx context.Context, r Request) ([]byte, error) {
+	if r.Channel != "" {
+		q := c.acquire(r.Channel)
+		defer c.release(r.Channel, q)
+		if err := q.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer q.unlock()
+	}
+
+	b := c.bucket(r)
+	backoff := c.Backoff
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if err := sleep(ctx, b.reserve()); err != nil {
+			return nil, err
+		}
+
+		body, status, header, err := c.send(ctx, r)
+		switch {
+		case err != nil:
+			lastErr = err
+		case status == http.StatusTooManyRequests:
+			retryAfter := parseRetryAfter(header.Get("Retry-After"))
+			b.block(retryAfter)
+			lastErr = &RateLimitError{Method: r.Method, RetryAfter: retryAfter}
+			log.Printf("Slack %s: Rate-Limit, warte %s", r.Method, retryAfter)
+			continue
+		case status >= 500:
+			lastErr = &HTTPError{Method: r.Method, StatusCode: status, Status: http.StatusText(status)}
+		case status != http.StatusOK:
+			return body, &HTTPError{Method: r.Method, StatusCode: status, Status: http.StatusText(status)}
+		default:
+			return body, nil
+		}
+
+		// Bei 429 hat Slack den Aufruf abgelehnt, bei 5xx und Netzwerkfehlern ist das offen
+		if !idempotentMethods[r.Method] {
+			return nil, lastErr
+		}
+		if attempt < c.MaxRetries {
+			log.Printf("Slack %s fehlgeschlagen (%v), neuer Versuch in %s", r.Method, lastErr, backoff)
+			if err := sleep(ctx, backoff); err != nil {
+				return nil, err
+			}
+			backoff *= 2
+		}
+	}
+
+	return nil, lastErr
+}
+
+func (c *Client) send(ctx context.Context, r Request) ([]byte, int, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	req.Header.Set("Content-Type", r.ContentType)
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return body, resp.StatusCode, resp.Header, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// bucket ist ein Token-Bucket für Token und Methode (bzw. Token, Methode und Kanal).
+type bucket struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	burst := float64(perMinute) / 10
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		rate:   float64(perMinute) / 60,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve verbraucht ein Token und liefert, wie lange bis zur Ausführung gewartet werden muss.
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if blocked := b.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	return wait
+}
+
+func (b *bucket) block(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// fifo ist eine Sperre, die Wartende in der Reihenfolge ihres Eintreffens bedient.
+type fifo struct {
+	mu      sync.Mutex
+	busy    bool
+	waiters []chan struct{}
+	// users zählt die Aufrufe zwischen acquire und release, geschützt von Client.mu
+	users int
+}
+
+// lock wartet, bis die Sperre frei ist oder ctx endet.
+func (f *fifo) lock(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.busy {
+		f.busy = true
+		f.mu.Unlock()
+		return nil
+	}
+	wait := make(chan struct{})
+	f.waiters = append(f.waiters, wait)
+	f.mu.Unlock()
+
+	select {
+	case <-wait:
+		return nil
+	case <-ctx.Done():
+	}
+
+	f.mu.Lock()
+	for i, w := range f.waiters {
+		if w == wait {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	f.mu.Unlock()
+	// unlock hat die Sperre gleichzeitig übergeben, sie geht an den Nächsten weiter
+	f.unlock()
+	return ctx.Err()
+}
+
+func (f *fifo) unlock() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.waiters) == 0 {
+		f.busy = false
+		return
+	}
+	next := f.waiters[0]
+	f.waiters = f.waiters[1:]
+	close(next)
+}
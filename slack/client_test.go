@@ -0,0 +1,237 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient liefert einen Client ohne Wartezeit zwischen den Versuchen.
+func testClient() *Client {
+	c := NewClient()
+	c.Backoff = time.Millisecond
+	return c
+}
+
+// statusServer antwortet nacheinander mit statuses, danach immer mit 200.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			if statuses[n-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		calls    int32
+		ok       bool
+	}{
+		{"5xx bei Nachricht", "chat.postMessage", []int{500}, 1, false},
+		{"5xx bei Upload", "files.completeUploadExternal", []int{502}, 1, false},
+		{"5xx beim Lesen", "auth.test", []int{500, 503}, 3, true},
+		{"5xx bei chat.update", "chat.update", []int{502}, 2, true},
+		{"5xx bei views.publish", "views.publish", []int{503}, 2, true},
+		{"5xx beim Löschen geplanter Nachricht", "chat.deleteScheduledMessage", []int{500}, 2, true},
+		{"429 bei Nachricht", "chat.postMessage", []int{429}, 2, true},
+		{"400", "users.list", []int{400}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusServer(t, tt.statuses...)
+			_, err := testClient().Do(context.Background(), Request{Method: tt.method, URL: server.URL, Channel: "C1"})
+			if (err == nil) != tt.ok {
+				t.Fatalf("Fehler %v, erwartet Erfolg: %v", err, tt.ok)
+			}
+			if n := atomic.LoadInt32(calls); n != tt.calls {
+				t.Fatalf("%d Aufrufe, erwartet %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestClientNetworkErrors(t *testing.T) {
+	tests := []struct {
+		method string
+		calls  int32
+	}{
+		{"chat.postMessage", 1},
+		{"auth.test", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var calls int32
+			// Der Server bricht jede Verbindung ohne Antwort ab
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			}))
+			defer server.Close()
+
+			if _, err := testClient().Do(context.Background(), Request{Method: tt.method, URL: server.URL}); err == nil {
+				t.Fatal("abgebrochene Verbindung liefert keinen Fehler")
+			}
+			if n := atomic.LoadInt32(&calls); n != tt.calls {
+				t.Fatalf("%d Aufrufe, erwartet %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestClientQueueHonoursContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := testClient()
+	go c.Do(context.Background(), Request{Method: "chat.update", URL: server.URL, Channel: "C1"})
+	for c.queued("C1") == nil || !c.queued("C1").isBusy() {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do(ctx, Request{Method: "chat.update", URL: server.URL, Channel: "C1"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Fehler %v, erwartet DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("abgebrochene Anfrage hängt in der Kanal-Warteschlange")
+	}
+	if n := c.queued("C1").waiting(); n != 0 {
+		t.Fatalf("%d Wartende nach Abbruch, erwartet 0", n)
+	}
+}
+
+func TestFifoOrder(t *testing.T) {
+	var f fifo
+	f.lock(context.Background())
+
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		i := i
+		go func() {
+			f.lock(context.Background())
+			order <- i
+			f.unlock()
+		}()
+		for f.waiting() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	f.unlock()
+
+	for want := 0; want < 3; want++ {
+		if got := <-order; got != want {
+			t.Fatalf("Reihenfolge: %d statt %d", got, want)
+		}
+	}
+}
+
+// TestClientRateLimitPerToken prüft, dass ein 429 nur die Aufrufe mit demselben Token bremst.
+func TestClientRateLimitPerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer xoxb-A" {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := testClient()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, Request{Method: "views.publish", URL: server.URL, Token: "xoxb-A"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Fehler %v, erwartet Warten auf Retry-After", err)
+	}
+
+	start := time.Now()
+	if _, err := c.Do(context.Background(), Request{Method: "views.publish", URL: server.URL, Token: "xoxb-B"}); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("anderer Workspace hat %s gewartet", waited)
+	}
+	if b := c.bucket(Request{Method: "views.publish", Token: "xoxb-A"}); b.reserve() < 20*time.Second {
+		t.Fatal("Token A ist nicht mehr gesperrt")
+	}
+}
+
+// TestClientDropsIdleQueues prüft, dass Warteschlangen nach dem letzten Aufruf verschwinden.
+func TestClientDropsIdleQueues(t *testing.T) {
+	server, _ := statusServer(t)
+	c := testClient()
+	c.Limits = map[string]int{"chat.update": 6000}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(channel string) {
+			defer wg.Done()
+			c.Do(context.Background(), Request{Method: "chat.update", URL: server.URL, Channel: channel})
+		}(fmt.Sprintf("C%d", i%5))
+	}
+	wg.Wait()
+
+	// Auch ein abgebrochener Wartender hinterlässt keine Warteschlange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Do(ctx, Request{Method: "chat.update", URL: server.URL, Channel: "C9"})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queues) != 0 {
+		t.Fatalf("%d Warteschlangen ohne Aufrufe übrig", len(c.queues))
+	}
+}
+
+// queued liefert die Warteschlange des Kanals, ohne sie anzulegen.
+func (c *Client) queued(channel string) *fifo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queues[channel]
+}
+
+func (f *fifo) waiting() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func (f *fifo) isBusy() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.busy
+}
@@ -0,0 +1,207 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"go-slack-ics/i18n"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParseCommand liest die Formularfelder, die Slack bei einem Slash-Befehl schickt.
+func ParseCommand(values url.Values) Command {
+	return Command{
+		Token:               values.Get("token"),
+		TeamID:              values.Get("team_id"),
+		TeamDomain:          values.Get("team_domain"),
+		ChannelID:           values.Get("channel_id"),
+		ChannelName:         values.Get("channel_name"),
+		UserID:              values.Get("user_id"),
+		UserName:            values.Get("user_name"),
+		Command:             values.Get("command"),
+		Text:                strings.TrimSpace(values.Get("text")),
+		APIAppID:            values.Get("api_app_id"),
+		IsEnterpriseInstall: values.Get("is_enterprise_install") == "true",
+		ResponseURL:         values.Get("response_url"),
+		TriggerID:           values.Get("trigger_id"),
+	}
+}
+
+// CommandRequest ist ein Slash-Befehl, wie ihn der Handler bekommt: Args ist der Text nach dem
+// Unterbefehl, Parsed das Ergebnis des ArgParser.
+type CommandRequest struct {
+	Command
+	Subcommand string
+	Args       string
+	Parsed     interface{}
+	Locale     string
+}
+
+// CommandHandler beantwortet einen Slash-Befehl. Die Antwort muss innerhalb von drei Sekunden
+// vorliegen, längere Arbeit läuft im Hintergrund und antwortet über die response_url.
+type CommandHandler func(ctx context.Context, req *CommandRequest) (ResponseMessage, error)
+
+// ArgParser prüft die Argumente und wandelt sie für CommandRequest.Parsed um. Die Fehlermeldung
+// sieht der User, daher bekommt der Parser dessen Sprache.
+type ArgParser func(args string, locale string) (interface{}, error)
+
+// Subcommand ist ein Unterbefehl wie "stats" in "/abfuhr stats 2024". Help und Usage sind
+// i18n-Schlüssel oder feste Texte.
+type Subcommand struct {
+	Name    string
+	Aliases []string
+	Usage   string
+	Help    string
+	Args    ArgParser
+	Handler CommandHandler
+}
+
+// CommandSpec beschreibt einen Slash-Befehl. Ohne Unterbefehle bekommt Handler den ganzen Text,
+// mit Unterbefehlen nur den Text, der zu keinem Unterbefehl passt (sofern Handler gesetzt ist).
+type CommandSpec struct {
+	Name        string
+	Description string
+	Usage       string
+	Args        ArgParser
+	Handler     CommandHandler
+	Subcommands []Subcommand
+}
+
+func (s *CommandSpec) subcommand(name string) (Subcommand, bool) {
+	for _, sub := range s.Subcommands {
+		if strings.EqualFold(sub.Name, name) {
+			return sub, true
+		}
+		for _, alias := range sub.Aliases {
+			if strings.EqualFold(alias, name) {
+				return sub, true
+			}
+		}
+	}
+	return Subcommand{}, false
+}
+
+// CommandRouter verteilt Slash-Befehle an die registrierten Handler und erzeugt die Hilfe.
+type CommandRouter struct {
+	commands map[string]*CommandSpec
+	// Locale bestimmt die Sprache der Antworten für einen User, ohne Angabe gilt i18n.Default.
+	Locale func(userID string) string
+}
+
+func NewCommandRouter() *CommandRouter {
+	return &CommandRouter{commands: make(map[string]*CommandSpec)}
+}
+
+// Register meldet einen Befehl an. Der Name beginnt mit "/", ein zweiter Aufruf ersetzt ihn.
+func (r *CommandRouter) Register(spec CommandSpec) {
+	r.commands[spec.Name] = &spec
+}
+
+// AddSubcommand ergänzt einen bereits registrierten Befehl um einen Unterbefehl aus einem
+// anderen Paket.
+func (r *CommandRouter) AddSubcommand(command string, sub Subcommand) {
+	spec, ok := r.commands[command]
+	if !ok {
+		spec = &CommandSpec{Name: command}
+		r.commands[command] = spec
+	}
+	spec.Subcommands = append(spec.Subcommands, sub)
+}
+
+// Commands liefert die Namen aller Befehle, sortiert.
+func (r *CommandRouter) Commands() []string {
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dispatch beantwortet den Befehl. "help" oder "hilfe" als erstes Wort liefert die Hilfe,
+// unbekannte Befehle und Unterbefehle bekommen einen Hinweis auf die möglichen Eingaben.
+func (r *CommandRouter) Dispatch(ctx context.Context, command Command) ResponseMessage {
+	return r.DispatchAs(ctx, command.Command, command)
+}
+
+// DispatchAs behandelt den Befehl als name, z. B. für Routen, die Slack für einen Befehl mit
+// anderem Namen aufruft.
+func (r *CommandRouter) DispatchAs(ctx context.Context, name string, command Command) ResponseMessage {
+	locale := i18n.Default()
+	if r.Locale != nil {
+		locale = r.Locale(command.UserID)
+	}
+	if command.Command == "" {
+		command.Command = name
+	}
+
+	spec, ok := r.commands[name]
+	if !ok {
+		return EphemeralResponse(i18n.T(locale, "command.unknown", name) + "\n" +
+			i18n.T(locale, "command.available", strings.Join(r.Commands(), ", ")))
+	}
+
+	first, rest, _ := strings.Cut(strings.TrimSpace(command.Text), " ")
+	if first == "help" || first == "hilfe" {
+		return EphemeralResponse(r.help(spec, command.Command, locale))
+	}
+
+	req := &CommandRequest{Command: command, Args: strings.TrimSpace(command.Text), Locale: locale}
+	// usageKey ist der i18n-Schlüssel, übersetzt wird erst in der Fehlermeldung
+	handler, parser, usageKey, shown := spec.Handler, spec.Args, spec.Usage, command.Command
+	if sub, ok := spec.subcommand(first); ok {
+		req.Subcommand, req.Args = sub.Name, strings.TrimSpace(rest)
+		handler, parser, usageKey, shown = sub.Handler, sub.Args, sub.Usage, command.Command+" "+sub.Name
+	} else if len(spec.Subcommands) > 0 && handler == nil && first != "" {
+		return EphemeralResponse(i18n.T(locale, "command.unknown_subcommand", command.Command, first) + "\n\n" +
+			r.help(spec, command.Command, locale))
+	}
+	if handler == nil {
+		return EphemeralResponse(r.help(spec, command.Command, locale))
+	}
+
+	if parser != nil {
+		parsed, err := parser(req.Args, locale)
+		if err != nil {
+			return EphemeralResponse(i18n.T(locale, "command.invalid_args", err.Error(),
+				strings.TrimSpace(shown+" "+i18n.T(locale, usageKey))))
+		}
+		req.Parsed = parsed
+	}
+
+	response, err := handler(ctx, req)
+	if err != nil {
+		return EphemeralResponse(i18n.T(locale, "error.generic", err.Error()))
+	}
+	return response
+}
+
+// help listet die Unterbefehle mit ihren Beschreibungen in der Sprache des Users.
+func (r *CommandRouter) help(spec *CommandSpec, shown string, locale string) string {
+	var b strings.Builder
+	if spec.Description != "" {
+		fmt.Fprintf(&b, "*%s* – %s\n", shown, i18n.T(locale, spec.Description))
+	} else {
+		fmt.Fprintf(&b, "*%s*\n", shown)
+	}
+	if spec.Handler != nil && spec.Usage != "" {
+		fmt.Fprintf(&b, "• `%s %s`\n", shown, i18n.T(locale, spec.Usage))
+	}
+	for _, sub := range spec.Subcommands {
+		usage := strings.TrimSpace(sub.Name + " " + i18n.T(locale, sub.Usage))
+		fmt.Fprintf(&b, "• `%s %s` – %s\n", shown, usage, i18n.T(locale, sub.Help))
+	}
+	fmt.Fprintf(&b, "• `%s help` – %s", shown, i18n.T(locale, "command.help"))
+	return b.String()
+}
+
+// EphemeralResponse ist eine Antwort, die nur der aufrufende User sieht.
+func EphemeralResponse(text string) ResponseMessage {
+	return ResponseMessage{Message: Message{Text: text}, ResponseType: "ephemeral"}
+}
+
+// InChannelResponse ist eine Antwort, die der ganze Kanal sieht.
+func InChannelResponse(text string) ResponseMessage {
+	return ResponseMessage{Message: Message{Text: text}, ResponseType: "in_channel"}
+}
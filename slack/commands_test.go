@@ -0,0 +1,111 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testRouter hat /abfuhr mit Unterbefehlen und /bild ohne. UEN schreibt Englisch, alle anderen
+// Deutsch.
+func testRouter(handled *[]string) *CommandRouter {
+	handler := func(_ context.Context, req *CommandRequest) (ResponseMessage, error) {
+		*handled = append(*handled, req.Subcommand+"|"+req.Args)
+		return EphemeralResponse("ok"), nil
+	}
+	year := func(args string, locale string) (interface{}, error) {
+		if args != "" && args != "2024" {
+			return nil, errors.New("kein Jahr")
+		}
+		return 2024, nil
+	}
+
+	r := NewCommandRouter()
+	r.Locale = func(userID string) string {
+		if userID == "UEN" {
+			return "en"
+		}
+		return "de"
+	}
+	r.Register(CommandSpec{
+		Name:        "/abfuhr",
+		Description: "command.abfuhr",
+		Subcommands: []Subcommand{
+			{Name: "stats", Usage: "command.abfuhr.stats.usage", Help: "command.abfuhr.stats", Args: year, Handler: handler},
+			{Name: "erledigt", Aliases: []string{"done"}, Help: "command.abfuhr.erledigt", Handler: handler},
+		},
+	})
+	r.Register(CommandSpec{
+		Name:        "/bild",
+		Description: "command.text_to_image",
+		Usage:       "command.image.usage",
+		Args: func(args string, locale string) (interface{}, error) {
+			if args == "" {
+				return nil, errors.New("leer")
+			}
+			return args, nil
+		},
+		Handler: handler,
+	})
+	return r
+}
+
+func TestCommandRouter(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		// contains muss in der Antwort stehen, handled ist "Unterbefehl|Argumente" des Handlers
+		contains []string
+		handled  string
+	}{
+		{"help", Command{Command: "/abfuhr", Text: "help"}, []string{"*/abfuhr* – Abfuhrtermine", "`/abfuhr stats [Jahr]` – Statistik", "`/abfuhr help`"}, ""},
+		{"hilfe", Command{Command: "/abfuhr", Text: "hilfe"}, []string{"`/abfuhr erledigt`"}, ""},
+		{"help englisch", Command{Command: "/abfuhr", Text: "help", UserID: "UEN"}, []string{"`/abfuhr stats [year]` – statistics"}, ""},
+		{"ohne text", Command{Command: "/abfuhr"}, []string{"`/abfuhr help`"}, ""},
+		{"unbekannter befehl", Command{Command: "/gibtsnicht"}, []string{"/gibtsnicht kenne ich nicht", "/abfuhr, /bild"}, ""},
+		{"unbekannter unterbefehl", Command{Command: "/abfuhr", Text: "quatsch 1"}, []string{"/abfuhr kennt „quatsch“ nicht", "`/abfuhr stats [Jahr]`"}, ""},
+		{"unterbefehl", Command{Command: "/abfuhr", Text: "stats 2024"}, []string{"ok"}, "stats|2024"},
+		{"groß geschrieben", Command{Command: "/abfuhr", Text: "STATS"}, []string{"ok"}, "stats|"},
+		{"alias", Command{Command: "/abfuhr", Text: "done"}, []string{"ok"}, "erledigt|"},
+		{"parserfehler", Command{Command: "/abfuhr", Text: "stats neunzehn"}, []string{"kein Jahr\nVerwendung: `/abfuhr stats [Jahr]`"}, ""},
+		{"parserfehler englisch", Command{Command: "/abfuhr", Text: "stats neunzehn", UserID: "UEN"}, []string{"Usage: `/abfuhr stats [year]`"}, ""},
+		{"ohne unterbefehle", Command{Command: "/bild", Text: "eine Tonne im Regen"}, []string{"ok"}, "|eine Tonne im Regen"},
+		{"parserfehler ohne unterbefehle", Command{Command: "/bild"}, []string{"leer\nVerwendung: `/bild <Beschreibung>`"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled []string
+			response := testRouter(&handled).Dispatch(context.Background(), tt.command)
+			for _, want := range tt.contains {
+				if !strings.Contains(response.Text, want) {
+					t.Errorf("Antwort %q enthält %q nicht", response.Text, want)
+				}
+			}
+			if response.ResponseType != "ephemeral" {
+				t.Errorf("response_type %s", response.ResponseType)
+			}
+			if got := strings.Join(handled, ","); got != tt.handled {
+				t.Errorf("Handler bekam %q, erwartet %q", got, tt.handled)
+			}
+		})
+	}
+}
+
+func TestCommandRouterDispatchAs(t *testing.T) {
+	var handled []string
+	r := testRouter(&handled)
+	r.AddSubcommand("/abfuhr", Subcommand{Name: "sprache", Aliases: []string{"language"}, Handler: func(_ context.Context, req *CommandRequest) (ResponseMessage, error) {
+		return EphemeralResponse(req.Command.Command + " " + req.Subcommand + " " + req.Args), nil
+	}})
+
+	// Die alte Route /abfuhr-alt wird als /abfuhr behandelt, die Hilfe zeigt den aufgerufenen Namen
+	response := r.DispatchAs(context.Background(), "/abfuhr", Command{Command: "/abfuhr-alt", Text: "language en"})
+	if response.Text != "/abfuhr-alt sprache en" {
+		t.Fatalf("Antwort %q", response.Text)
+	}
+	response = r.DispatchAs(context.Background(), "/abfuhr", Command{Command: "/abfuhr-alt", Text: "help"})
+	if !strings.Contains(response.Text, "`/abfuhr-alt sprache`") {
+		t.Fatalf("Hilfe %q ohne den neuen Unterbefehl", response.Text)
+	}
+}
@@ -0,0 +1,56 @@
+package slack
+
+import "log"
+
+// EventHandler verarbeitet ein Event aus der Events API.
+type EventHandler func(payload Payload)
+
+// EventDispatcher verteilt Events API Payloads nach Typ und Subtyp an registrierte Handler.
+type EventDispatcher struct {
+	handlers map[string]EventHandler
+	seen     func(eventID string) bool
+}
+
+// NewEventDispatcher erzeugt einen Dispatcher. seen meldet, ob eine event_id schon verarbeitet
+// wurde; Slack schickt Events bei Timeouts erneut (X-Slack-Retry-Num).
+func NewEventDispatcher(seen func(eventID string) bool) *EventDispatcher {
+	return &EventDispatcher{
+		handlers: make(map[string]EventHandler),
+		seen:     seen,
+	}
+}
+
+// On registriert einen Handler für einen Event-Typ ("message") oder Typ und Subtyp
+// ("message.bot_message"). Der spezifischere Handler gewinnt.
+func (d *EventDispatcher) On(eventType string, handler EventHandler) {
+	d.handlers[eventType] = handler
+}
+
+func (d *EventDispatcher) handler(event Event) EventHandler {
+	if event.Subtype != "" {
+		if handler, ok := d.handlers[event.Type+"."+event.Subtype]; ok {
+			return handler
+		}
+	}
+	return d.handlers[event.Type]
+}
+
+// Dispatch startet den passenden Handler im Hintergrund, damit Slack innerhalb von drei
+// Sekunden seine Bestätigung bekommt. Doppelte Events werden verworfen.
+func (d *EventDispatcher) Dispatch(payload Payload) bool {
+	if payload.Type != "event_callback" && payload.Type != "" {
+		return false
+	}
+	if payload.EventID != "" && d.seen != nil && d.seen(payload.EventID) {
+		log.Printf("Event %s wurde bereits verarbeitet", payload.EventID)
+		return false
+	}
+
+	handler := d.handler(payload.Event)
+	if handler == nil {
+		return false
+	}
+
+	go handler(payload)
+	return true
+}
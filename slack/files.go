@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// File ist eine Datei für UploadFiles.
+type File struct {
+	Name    string
+	Title   string
+	AltText string
+	Data    []byte
+}
+
+// UploadFiles lädt die Dateien über files.getUploadURLExternal und files.completeUploadExternal
+// hoch und teilt sie in einer Nachricht im Kanal bzw. im Thread von threadTs. Response.Files
+// enthält die IDs und Permalinks. files.completeUploadExternal liefert nur id und title, die
+// Permalinks kommen einzeln über files.info.
+func (s *Slack) UploadFiles(ctx context.Context, files []File, channel, threadTs, comment string) (Response, error) {
+	type completeFile struct {
+		ID    string `json:"id"`
+		Title string `json:"title,omitempty"`
+	}
+	var uploaded []completeFile
+
+	for _, file := range files {
+		id, err := s.uploadFile(ctx, file, channel)
+		if err != nil {
+			return Response{}, err
+		}
+		uploaded = append(uploaded, completeFile{ID: id, Title: file.Title})
+	}
+
+	filesJSON, err := json.Marshal(uploaded)
+	if err != nil {
+		return Response{}, err
+	}
+	form := url.Values{}
+	form.Set("files", string(filesJSON))
+	form.Set("channel_id", channel)
+	if threadTs != "" {
+		form.Set("thread_ts", threadTs)
+	}
+	if comment != "" {
+		form.Set("initial_comment", comment)
+	}
+
+	var response Response
+	if err := s.postForm(ctx, "files.completeUploadExternal", channel, form, &response); err != nil {
+		return response, err
+	}
+
+	for i, file := range response.Files {
+		if file.Permalink != "" {
+			continue
+		}
+		info, err := s.FileInfo(ctx, file.ID)
+		if err != nil {
+			// Die Dateien sind geteilt, nur der Link fehlt
+			log.Printf("Permalink für %s nicht abrufbar: %v", file.ID, err)
+			continue
+		}
+		response.Files[i].Permalink = info.Permalink
+	}
+	return response, nil
+}
+
+// FileInfo liefert die Angaben zu einer Datei über files.info.
+func (s *Slack) FileInfo(ctx context.Context, id string) (UploadedFile, error) {
+	form := url.Values{}
+	form.Set("file", id)
+
+	var info struct {
+		File UploadedFile `json:"file"`
+	}
+	err := s.postForm(ctx, "files.info", "", form, &info)
+	return info.File, err
+}
+
+// uploadFile holt eine Upload-URL und überträgt die Datei dorthin. Geteilt wird sie erst durch
+// files.completeUploadExternal.
+func (s *Slack) uploadFile(ctx context.Context, file File, channel string) (string, error) {
+	form := url.Values{}
+	form.Set("filename", file.Name)
+	form.Set("length", strconv.Itoa(len(file.Data)))
+	if file.AltText != "" {
+		form.Set("alt_txt", file.AltText)
+	}
+
+	var target UploadURLResponse
+	if err := s.postForm(ctx, "files.getUploadURLExternal", channel, form, &target); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target.UploadURL, bytes.NewReader(file.Data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := DefaultClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Fehler beim Hochladen von %s: %w", file.Name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Fehler beim Hochladen von %s: %s", file.Name, resp.Status)
+	}
+
+	return target.FileID, nil
+}
+
+// postForm ruft eine Methode mit form-encoded Feldern auf und dekodiert die Antwort in target.
+func (s *Slack) postForm(ctx context.Context, method string, channel string, form url.Values, target interface{}) error {
+	body, err := DefaultClient.Do(ctx, Request{
+		Method:      method,
+		URL:         s.apiURL(method),
+		Channel:     channel,
+		ContentType: "application/x-www-form-urlencoded",
+		Token:       s.token(ctx),
+		Body:        []byte(form.Encode()),
+	})
+	if err != nil {
+		return err
+	}
+
+	var status Response
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("slack %s: ungültige Antwort: %w", method, err)
+	}
+	if !status.Ok {
+		return &SlackError{Method: method, Code: status.Error}
+	}
+	return json.Unmarshal(body, target)
+}
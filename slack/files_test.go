@@ -0,0 +1,48 @@
+package slack_test
+
+import (
+	"context"
+	"go-slack-ics/slack"
+	"go-slack-ics/slack/slacktest"
+	"testing"
+)
+
+func TestUploadFilesPermalink(t *testing.T) {
+	server := slacktest.NewServer()
+	defer server.Close()
+	client := slack.Slack{BaseURL: server.APIURL(), Token: "xoxb-test"}
+
+	response, err := client.UploadFiles(context.Background(), []slack.File{
+		{Name: "a.png", Title: "A", Data: []byte("a")},
+		{Name: "b.png", Title: "B", Data: []byte("b")},
+	}, "C1", "", "Bilder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Files) != 2 {
+		t.Fatalf("%d Dateien, erwartet 2", len(response.Files))
+	}
+	for _, file := range response.Files {
+		if file.Permalink != server.URL+"/files/"+file.ID {
+			t.Errorf("Permalink von %s = %q", file.ID, file.Permalink)
+		}
+	}
+	if n := len(server.Calls("files.info")); n != 2 {
+		t.Errorf("%d Aufrufe von files.info, erwartet 2", n)
+	}
+	if n := len(server.Calls("upload")); n != 2 {
+		t.Errorf("%d Uploads, erwartet 2", n)
+	}
+}
+
+func TestFileInfoUnknown(t *testing.T) {
+	server := slacktest.NewServer()
+	defer server.Close()
+	client := slack.Slack{BaseURL: server.APIURL(), Token: "xoxb-test"}
+
+	info, err := client.FileInfo(context.Background(), "FUNBEKANNT")
+	if err == nil {
+		t.Fatalf("files.info für unbekannte Datei liefert %+v", info)
+	}
+}
@@ -0,0 +1,270 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// InteractionPayload ist der JSON-Inhalt des Formularfelds "payload", das Slack bei Klicks,
+// Modals und Shortcuts an die Interactivity Request URL schickt.
+type InteractionPayload struct {
+	Type        string            `json:"type"`
+	Token       string            `json:"token"`
+	APIAppID    string            `json:"api_app_id"`
+	Team        InteractionTeam   `json:"team"`
+	User        InteractionUser   `json:"user"`
+	Channel     *InteractionPlace `json:"channel,omitempty"`
+	Container   Container         `json:"container"`
+	TriggerID   string            `json:"trigger_id"`
+	ResponseURL string            `json:"response_url,omitempty"`
+	// CallbackID gehört zu shortcut und message_action, bei Modals steht sie in View.
+	CallbackID   string          `json:"callback_id,omitempty"`
+	ActionTs     string          `json:"action_ts,omitempty"`
+	Message      *MessageDetail  `json:"message,omitempty"`
+	Actions      []Action        `json:"actions,omitempty"`
+	View         *View           `json:"view,omitempty"`
+	ResponseURLs []ResponseURLTo `json:"response_urls,omitempty"`
+}
+
+type InteractionTeam struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type InteractionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	TeamID   string `json:"team_id"`
+}
+
+type InteractionPlace struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Container beschreibt, wo das Element stand: in einer Nachricht oder in einem View.
+type Container struct {
+	Type        string `json:"type"`
+	MessageTs   string `json:"message_ts,omitempty"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	IsEphemeral bool   `json:"is_ephemeral,omitempty"`
+	ViewID      string `json:"view_id,omitempty"`
+}
+
+// Action ist ein ausgelöstes Element eines block_actions-Payloads.
+type Action struct {
+	Type           string  `json:"type"`
+	ActionID       string  `json:"action_id"`
+	BlockID        string  `json:"block_id"`
+	Value          string  `json:"value,omitempty"`
+	SelectedOption *Option `json:"selected_option,omitempty"`
+	SelectedDate   string  `json:"selected_date,omitempty"`
+	Text           *Text   `json:"text,omitempty"`
+	ActionTs       string  `json:"action_ts"`
+}
+
+// ResponseURLTo ist eine response_url, die ein Modal für einen Kanal mitliefert.
+type ResponseURLTo struct {
+	BlockID     string `json:"block_id"`
+	ActionID    string `json:"action_id"`
+	ChannelID   string `json:"channel_id"`
+	ResponseURL string `json:"response_url"`
+}
+
+// View ist ein Modal oder der App-Home-Tab.
+type View struct {
+	ID              string     `json:"id,omitempty"`
+	Type            string     `json:"type"`
+	CallbackID      string     `json:"callback_id,omitempty"`
+	Title           *Text      `json:"title,omitempty"`
+	Submit          *Text      `json:"submit,omitempty"`
+	Close           *Text      `json:"close,omitempty"`
+	Blocks          []Block    `json:"blocks"`
+	PrivateMetadata string     `json:"private_metadata,omitempty"`
+	ExternalID      string     `json:"external_id,omitempty"`
+	Hash            string     `json:"hash,omitempty"`
+	State           *ViewState `json:"state,omitempty"`
+}
+
+// ViewState enthält die Eingaben eines Modals nach block_id und action_id.
+type ViewState struct {
+	Values map[string]map[string]ActionValue `json:"values"`
+}
+
+type ActionValue struct {
+	Type           string  `json:"type"`
+	Value          string  `json:"value,omitempty"`
+	SelectedDate   string  `json:"selected_date,omitempty"`
+	SelectedOption *Option `json:"selected_option,omitempty"`
+}
+
+// ViewResponse ist die synchrone Antwort auf view_submission, z. B. Fehler an Eingabefeldern.
+type ViewResponse struct {
+	ResponseAction string            `json:"response_action"`
+	Errors         map[string]string `json:"errors,omitempty"`
+	View           *View             `json:"view,omitempty"`
+}
+
+// ViewErrors zeigt die Fehlermeldungen an den Eingabeblöcken mit den jeweiligen block_ids an.
+func ViewErrors(errors map[string]string) *ViewResponse {
+	return &ViewResponse{ResponseAction: "errors", Errors: errors}
+}
+
+// Interaction ist der Payload zusammen mit der Aktion, für die der Handler aufgerufen wurde.
+type Interaction struct {
+	InteractionPayload
+	Action Action
+}
+
+// ActionHandler verarbeitet einen Klick oder Shortcut. Er läuft im Hintergrund, Fehler werden
+// protokolliert.
+type ActionHandler func(ctx context.Context, interaction *Interaction) error
+
+// ViewHandler verarbeitet view_submission. Die Antwort muss innerhalb von drei Sekunden
+// vorliegen; nil schließt das Modal.
+type ViewHandler func(ctx context.Context, interaction *Interaction) *ViewResponse
+
+// InteractionDispatcher verteilt Interaktionen nach action_id bzw. callback_id.
+type InteractionDispatcher struct {
+	actions   map[string]ActionHandler
+	shortcuts map[string]ActionHandler
+	views     map[string]ViewHandler
+}
+
+func NewInteractionDispatcher() *InteractionDispatcher {
+	return &InteractionDispatcher{
+		actions:   make(map[string]ActionHandler),
+		shortcuts: make(map[string]ActionHandler),
+		views:     make(map[string]ViewHandler),
+	}
+}
+
+// Action registriert einen Handler für block_actions mit dieser action_id.
+func (d *InteractionDispatcher) Action(actionID string, handler ActionHandler) {
+	d.actions[actionID] = handler
+}
+
+// Shortcut registriert einen Handler für globale (shortcut) und Nachrichten-Shortcuts
+// (message_action) mit dieser callback_id.
+func (d *InteractionDispatcher) Shortcut(callbackID string, handler ActionHandler) {
+	d.shortcuts[callbackID] = handler
+}
+
+// ViewSubmission registriert einen Handler für das Absenden des Modals mit dieser callback_id.
+func (d *InteractionDispatcher) ViewSubmission(callbackID string, handler ViewHandler) {
+	d.views[callbackID] = handler
+}
+
+// Dispatch ruft die passenden Handler auf. Für view_submission wird die Antwort des Handlers
+// zurückgegeben, alle anderen Typen laufen im Hintergrund und liefern nil.
+func (d *InteractionDispatcher) Dispatch(ctx context.Context, payload InteractionPayload) *ViewResponse {
+	switch payload.Type {
+	case "block_actions":
+		for _, action := range payload.Actions {
+			handler, ok := d.actions[action.ActionID]
+			if !ok {
+				log.Printf("Kein Handler für action_id %s", action.ActionID)
+				continue
+			}
+			go run(handler, &Interaction{InteractionPayload: payload, Action: action})
+		}
+	case "shortcut", "message_action":
+		handler, ok := d.shortcuts[payload.CallbackID]
+		if !ok {
+			log.Printf("Kein Handler für callback_id %s", payload.CallbackID)
+			return nil
+		}
+		go run(handler, &Interaction{InteractionPayload: payload})
+	case "view_submission":
+		if payload.View == nil {
+			return nil
+		}
+		handler, ok := d.views[payload.View.CallbackID]
+		if !ok {
+			log.Printf("Kein Handler für callback_id %s", payload.View.CallbackID)
+			return nil
+		}
+		return handler(WithTeam(ctx, payload.Team.ID), &Interaction{InteractionPayload: payload})
+	default:
+		log.Printf("Interaktion vom Typ %s wird nicht verarbeitet", payload.Type)
+	}
+	return nil
+}
+
+func run(handler ActionHandler, interaction *Interaction) {
+	ctx := WithTeam(context.Background(), interaction.Team.ID)
+	if err := handler(ctx, interaction); err != nil {
+		log.Printf("Fehler bei der Interaktion %s%s: %v", interaction.Action.ActionID, interaction.CallbackID, err)
+	}
+}
+
+// ResponseMessage ist eine Nachricht an eine response_url.
+type ResponseMessage struct {
+	Message
+	// ResponseType ist "ephemeral" (Standard) oder "in_channel".
+	ResponseType    string `json:"response_type,omitempty"`
+	ReplaceOriginal bool   `json:"replace_original,omitempty"`
+	DeleteOriginal  bool   `json:"delete_original,omitempty"`
+}
+
+// Respond schickt eine Nachricht an die response_url der Interaktion.
+func (i *Interaction) Respond(ctx context.Context, message ResponseMessage) error {
+	responseURL := i.ResponseURL
+	if responseURL == "" && len(i.ResponseURLs) > 0 {
+		responseURL = i.ResponseURLs[0].ResponseURL
+	}
+	if responseURL == "" {
+		return &SlackError{Method: "response_url", Code: "no_response_url"}
+	}
+	return PostResponse(ctx, responseURL, message)
+}
+
+// ReplyEphemeral zeigt nur dem auslösenden Benutzer einen Hinweis an.
+func (i *Interaction) ReplyEphemeral(ctx context.Context, text string) error {
+	return i.Respond(ctx, ResponseMessage{
+		Message:      Message{Text: text},
+		ResponseType: "ephemeral",
+	})
+}
+
+// UpdateOriginal ersetzt die Nachricht, in der das Element stand. Ohne response_url wird
+// chat.update verwendet.
+func (i *Interaction) UpdateOriginal(ctx context.Context, message Message) error {
+	if i.ResponseURL != "" {
+		return i.Respond(ctx, ResponseMessage{Message: message, ReplaceOriginal: true})
+	}
+	_, err := Instance.ChangeMessage(ctx, i.Container.MessageTs, i.Container.ChannelID, "", message)
+	return err
+}
+
+// DeleteOriginal löscht die Nachricht, in der das Element stand.
+func (i *Interaction) DeleteOriginal(ctx context.Context) error {
+	return i.Respond(ctx, ResponseMessage{DeleteOriginal: true})
+}
+
+// PostResponse schickt eine Nachricht an eine response_url.
+func PostResponse(ctx context.Context, responseURL string, message ResponseMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	body, err := DefaultClient.Do(ctx, Request{
+		Method:      "response_url",
+		URL:         responseURL,
+		Channel:     message.Channel,
+		ContentType: "application/json; charset=utf-8",
+		Body:        payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Slack antwortet mit {"ok":true} oder nur mit "ok"
+	var status Response
+	if json.Unmarshal(body, &status) == nil && !status.Ok && status.Error != "" {
+		return &SlackError{Method: "response_url", Code: status.Error}
+	}
+	return nil
+}
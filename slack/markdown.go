@@ -0,0 +1,365 @@
+package slack
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength ist die Textmenge, die Slack in einer Nachricht annimmt. Längere Antworten
+// verteilt MarkdownMessages auf mehrere Nachrichten.
+const maxMessageLength = 40000
+
+var (
+	fencePattern     = regexp.MustCompile("^\\s*(```+|~~~+)")
+	headingPattern   = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	rulePattern      = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	tableRulePattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	bulletPattern    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	quotePattern     = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+
+	codeSpanPattern   = regexp.MustCompile("`[^`]+`")
+	slackTokenPattern = regexp.MustCompile(`<(?:[@#!]|https?://|mailto:)[^<>]*>`)
+	imagePattern      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	boldItalicPattern = regexp.MustCompile(`\*\*\*([^*]+)\*\*\*`)
+	// Fett darf einzelne * bzw. _ enthalten, z. B. **fett mit *kursiv* darin**
+	boldPattern   = regexp.MustCompile(`\*\*((?:[^*]|\*[^*])+?)\*\*|__((?:[^_]|_[^_])+?)__`)
+	italicPattern = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	strikePattern = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// MarkdownToMrkdwn übersetzt CommonMark, wie es GPT liefert, in Slacks mrkdwn: **fett** wird
+// *fett*, *kursiv* wird _kursiv_, Links werden zu <url|text>, Überschriften fett und Listen zu
+// Aufzählungszeichen. Tabellen und Codeblöcke landen als ```-Block im Text, für Blöcke ist
+// MarkdownBlocks besser.
+func MarkdownToMrkdwn(markdown string) string {
+	var parts []string
+	for _, node := range parseMarkdown(markdown) {
+		switch node.kind {
+		case markdownCode, markdownTable:
+			parts = append(parts, "```\n"+escapeMrkdwn(node.text)+"\n```")
+		case markdownHeading:
+			parts = append(parts, "*"+escapeMrkdwn(stripInline(node.text))+"*")
+		case markdownRule:
+			parts = append(parts, "———")
+		default:
+			parts = append(parts, node.text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MarkdownBlocks übersetzt Markdown in Blöcke: Überschriften werden header-Blöcke, Codeblöcke
+// und Tabellen rich_text-Blöcke mit vorformatiertem Text, der Rest mrkdwn-Sections. Texte über
+// dem Limit einer Section werden an Zeilengrenzen auf mehrere Blöcke verteilt.
+func MarkdownBlocks(markdown string) []Block {
+	var blocks []Block
+	for _, node := range parseMarkdown(markdown) {
+		switch node.kind {
+		case markdownHeading:
+			text := stripInline(node.text)
+			if utf8.RuneCountInString(text) <= maxHeaderLength {
+				blocks = append(blocks, Block{Type: "header", Text: PlainText(text)})
+				continue
+			}
+			for _, chunk := range splitText("*"+escapeMrkdwn(stripInline(node.text))+"*", maxTextLength) {
+				blocks = append(blocks, Block{Type: "section", Text: Mrkdwn(chunk)})
+			}
+		case markdownCode, markdownTable:
+			if strings.TrimSpace(node.text) == "" {
+				continue
+			}
+			for _, chunk := range splitText(node.text, maxTextLength) {
+				blocks = append(blocks, Block{Type: "rich_text", Elements: Elements{
+					&RichTextSection{Type: "rich_text_preformatted", Elements: Elements{
+						&RichTextElement{Type: "text", Text: chunk},
+					}},
+				}})
+			}
+		case markdownRule:
+			blocks = append(blocks, Block{Type: "divider"})
+		default:
+			for _, chunk := range splitText(node.text, maxTextLength) {
+				blocks = append(blocks, Block{Type: "section", Text: Mrkdwn(chunk)})
+			}
+		}
+	}
+	return blocks
+}
+
+// MarkdownMessages verteilt die Blöcke aus MarkdownBlocks auf so viele Nachrichten, wie die
+// Limits von Slack verlangen. Es gibt immer mindestens eine Nachricht.
+func MarkdownMessages(user string, channel string, markdown string) []Message {
+	var messages []Message
+	var current []Block
+	length := 0
+	for _, block := range MarkdownBlocks(markdown) {
+		size := blockLength(block)
+		if len(current) > 0 && (len(current) == MaxMessageBlocks || length+size > maxMessageLength) {
+			messages = append(messages, Message{User: user, Channel: channel, Blocks: current})
+			current, length = nil, 0
+		}
+		current = append(current, block)
+		length += size
+	}
+	if len(current) > 0 || len(messages) == 0 {
+		if len(current) == 0 {
+			// Slack lehnt Nachrichten ohne Text und Blöcke ab
+			current = []Block{{Type: "section", Text: Mrkdwn(" ")}}
+		}
+		messages = append(messages, Message{User: user, Channel: channel, Blocks: current})
+	}
+	return messages
+}
+
+func blockLength(block Block) int {
+	if block.Text != nil {
+		return utf8.RuneCountInString(block.Text.Text)
+	}
+	n := 0
+	for _, element := range block.Elements {
+		if section, ok := element.(*RichTextSection); ok {
+			for _, inner := range section.Elements {
+				if text, ok := inner.(*RichTextElement); ok {
+					n += utf8.RuneCountInString(text.Text)
+				}
+			}
+		}
+	}
+	return n
+}
+
+const (
+	markdownText = iota
+	markdownHeading
+	markdownCode
+	markdownTable
+	markdownRule
+)
+
+// markdownNode ist ein Abschnitt des Markdown-Texts. Bei markdownText ist text schon mrkdwn,
+// bei Code und Tabellen der vorformatierte Inhalt, bei Überschriften der Markdown-Text.
+type markdownNode struct {
+	kind int
+	text string
+}
+
+// parseMarkdown zerlegt den Text zeilenweise. Ein nicht geschlossener Codeblock reicht bis zum
+// Ende, damit halbe Antworten beim Streaming nicht springen.
+func parseMarkdown(markdown string) []markdownNode {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	var nodes []markdownNode
+	var paragraph []string
+
+	flush := func() {
+		text := strings.Trim(strings.Join(paragraph, "\n"), "\n")
+		if strings.TrimSpace(text) != "" {
+			nodes = append(nodes, markdownNode{kind: markdownText, text: text})
+		}
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			flush()
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), match[1]) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			nodes = append(nodes, markdownNode{kind: markdownCode, text: strings.Join(code, "\n")})
+			continue
+		}
+
+		if strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "|") && tableRulePattern.MatchString(lines[i+1]) {
+			flush()
+			rows := [][]string{tableCells(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				rows = append(rows, tableCells(lines[i]))
+			}
+			i--
+			nodes = append(nodes, markdownNode{kind: markdownTable, text: renderTable(rows)})
+			continue
+		}
+
+		if match := headingPattern.FindStringSubmatch(line); match != nil && match[2] != "" {
+			flush()
+			nodes = append(nodes, markdownNode{kind: markdownHeading, text: match[2]})
+			continue
+		}
+
+		if rulePattern.MatchString(line) {
+			flush()
+			nodes = append(nodes, markdownNode{kind: markdownRule})
+			continue
+		}
+
+		paragraph = append(paragraph, convertLine(line))
+	}
+	flush()
+	return nodes
+}
+
+// convertLine übersetzt eine Textzeile mit Listen- und Zitatmarken.
+func convertLine(line string) string {
+	if match := quotePattern.FindStringSubmatch(line); match != nil {
+		return "> " + convertInline(match[1])
+	}
+	if match := bulletPattern.FindStringSubmatch(line); match != nil {
+		depth := indentDepth(match[1])
+		bullet := "•"
+		if depth%2 == 1 {
+			bullet = "◦"
+		}
+		return strings.Repeat("    ", depth) + bullet + " " + convertInline(match[2])
+	}
+	if match := orderedPattern.FindStringSubmatch(line); match != nil {
+		return strings.Repeat("    ", indentDepth(match[1])) + match[2] + ". " + convertInline(match[3])
+	}
+	return convertInline(strings.TrimRight(line, " "))
+}
+
+func indentDepth(indent string) int {
+	return len(strings.ReplaceAll(indent, "\t", "    ")) / 2
+}
+
+// convertInline übersetzt Hervorhebungen und Links. Code-Spans bleiben unverändert, Slack kennt
+// dieselbe Schreibweise.
+func convertInline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range codeSpanPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(convertEmphasis(text[last:span[0]]))
+		b.WriteString(escapeMrkdwn(text[span[0]:span[1]]))
+		last = span[1]
+	}
+	b.WriteString(convertEmphasis(text[last:]))
+	return b.String()
+}
+
+func convertEmphasis(text string) string {
+	text = escapeMrkdwn(text)
+	text = imagePattern.ReplaceAllString(text, "<$2|$1>")
+	text = linkPattern.ReplaceAllString(text, "<$2|$1>")
+	// Fett wird vor kursiv und erst zu \x00 übersetzt, sonst hielte die Kursiv-Regel *fett* für
+	// kursiv. Kursives innerhalb von Fett wird danach zu _kursiv_.
+	text = boldItalicPattern.ReplaceAllString(text, "\x00_${1}_\x00")
+	text = boldPattern.ReplaceAllString(text, "\x00$1$2\x00")
+	text = italicPattern.ReplaceAllString(text, "${1}_${2}_")
+	text = strikePattern.ReplaceAllString(text, "~$1~")
+	return strings.ReplaceAll(text, "\x00", "*")
+}
+
+// escapeMrkdwn maskiert &, < und >, lässt aber Erwähnungen und Links im Slack-Format stehen.
+func escapeMrkdwn(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, token := range slackTokenPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(escapeEntities(text[last:token[0]]))
+		b.WriteString(text[token[0]:token[1]])
+		last = token[1]
+	}
+	b.WriteString(escapeEntities(text[last:]))
+	return b.String()
+}
+
+var entityReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeEntities(text string) string {
+	return entityReplacer.Replace(text)
+}
+
+// stripInline entfernt die Markdown-Zeichen für Stellen ohne Formatierung, z. B. header-Blöcke
+// und Tabellenzellen.
+func stripInline(text string) string {
+	text = imagePattern.ReplaceAllString(text, "$1")
+	text = linkPattern.ReplaceAllString(text, "$1 ($2)")
+	return strings.NewReplacer("**", "", "__", "", "~~", "", "`", "").Replace(text)
+}
+
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = stripInline(strings.TrimSpace(cell))
+	}
+	return cells
+}
+
+// renderTable richtet die Spalten für eine Darstellung in Festbreitenschrift aus.
+func renderTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for r, row := range rows {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+		if r == 0 {
+			rule := make([]string, len(widths))
+			for i, width := range widths {
+				rule[i] = strings.Repeat("-", width)
+			}
+			lines = append(lines, strings.Join(rule, "-+-"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitText teilt text in Stücke von höchstens max Zeichen, bevorzugt an Zeilenumbrüchen, dann
+// an Leerzeichen. Das Trennzeichen fällt weg, Einrückungen im Code bleiben erhalten.
+func splitText(text string, max int) []string {
+	var chunks []string
+	for utf8.RuneCountInString(text) > max {
+		cut := runeOffset(text, max)
+		if i := strings.LastIndex(text[:cut], "\n"); i > 0 {
+			cut = i
+		} else if i := strings.LastIndex(text[:cut], " "); i > 0 {
+			cut = i
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+		if text[0] == '\n' || text[0] == ' ' {
+			text = text[1:]
+		}
+	}
+	if text != "" || len(chunks) == 0 {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// runeOffset liefert den Byte-Index nach n Zeichen.
+func runeOffset(text string, n int) int {
+	for i := range text {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(text)
+}
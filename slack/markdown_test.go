@@ -0,0 +1,177 @@
+package slack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMarkdownToMrkdwn(t *testing.T) {
+	tests := []struct {
+		name, markdown, want string
+	}{
+		{"fett", "**fett**", "*fett*"},
+		{"fett mit unterstrichen", "__fett__", "*fett*"},
+		{"kursiv", "*kursiv*", "_kursiv_"},
+		{"kursiv in fett", "**fett mit *kursiv* darin**", "*fett mit _kursiv_ darin*"},
+		{"fett in kursiv", "*kursiv mit **fett** darin*", "_kursiv mit *fett* darin_"},
+		{"zweimal fett", "**eins** und **zwei**", "*eins* und *zwei*"},
+		{"fett und kursiv", "***beides***", "*_beides_*"},
+		{"durchgestrichen", "~~weg~~", "~weg~"},
+		{"kein kursiv", "2 * 3 * 4", "2 * 3 * 4"},
+		{"link", "[AWB](https://www.awbkoeln.de)", "<https://www.awbkoeln.de|AWB>"},
+		{"bild", "![Tonne](https://example.org/t.png)", "<https://example.org/t.png|Tonne>"},
+		{"entities", "a < b & c > d", "a &lt; b &amp; c &gt; d"},
+		{"erwähnung", "<@U123> und <#C123>", "<@U123> und <#C123>"},
+		{"code-span", "`**kein fett**` aber **fett**", "`**kein fett**` aber *fett*"},
+		{"überschrift", "## Abfuhr **morgen**", "*Abfuhr morgen*"},
+		{"liste", "- eins\n  - zwei\n* drei", "• eins\n    ◦ zwei\n• drei"},
+		{"nummeriert", "1) erstens\n2. zweitens", "1. erstens\n2. zweitens"},
+		{"zitat", "> **wichtig**", "> *wichtig*"},
+		{"codeblock", "```go\nx := a < b\n```", "```\nx := a &lt; b\n```"},
+		{"offener codeblock", "Text\n```\nnoch offen", "Text\n```\nnoch offen\n```"},
+		{"linie", "oben\n---\nunten", "oben\n———\nunten"},
+		{"tabelle", "| Tonne | Tag |\n|---|:--:|\n| Bio | **Mo** |", "```\nTonne | Tag\n------+----\nBio   | Mo\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToMrkdwn(tt.markdown); got != tt.want {
+				t.Errorf("\n%q\nergibt %q\nerwartet %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	code := strings.Repeat("fmt.Println(\"eine Zeile mit etwas Code\")\n", 200)
+	tests := []struct {
+		name     string
+		markdown string
+		types    []string
+	}{
+		{"gemischt", "# Titel\nText\n```\ncode\n```\n---\n| a | b |\n|---|---|\n| 1 | 2 |", []string{"header", "section", "rich_text", "divider", "rich_text"}},
+		{"lange überschrift", "# " + strings.Repeat("Titel ", 30), []string{"section"}},
+		{"langer text", strings.Repeat("Wort ", 1400), []string{"section", "section", "section"}},
+		{"langer code", "```\n" + code + "```", []string{"rich_text", "rich_text", "rich_text"}},
+		{"leerer code", "```\n```", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := MarkdownBlocks(tt.markdown)
+			var types []string
+			for _, block := range blocks {
+				types = append(types, block.Type)
+				if n := blockLength(block); n > maxTextLength {
+					t.Errorf("%s-Block mit %d Zeichen", block.Type, n)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.types) {
+				t.Fatalf("Blöcke %v, erwartet %v", types, tt.types)
+			}
+			if err := ValidateBlocks(blocks, len(blocks)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestMarkdownBlocksCodeStaysPreformatted prüft, dass ein aufgeteilter Codeblock nur an
+// Zeilengrenzen getrennt wird und kein Stück als mrkdwn mit halbem ``` landet.
+func TestMarkdownBlocksCodeStaysPreformatted(t *testing.T) {
+	line := "\tif tonne.Voll() { abholen() }"
+	code := strings.TrimSuffix(strings.Repeat(line+"\n", 300), "\n")
+	blocks := MarkdownBlocks("Vorher\n```go\n" + code + "\n```\nNachher")
+
+	var chunks []string
+	for _, block := range blocks[1 : len(blocks)-1] {
+		if block.Type != "rich_text" {
+			t.Fatalf("%s-Block mitten im Code", block.Type)
+		}
+		section := block.Elements[0].(*RichTextSection)
+		chunks = append(chunks, section.Elements[0].(*RichTextElement).Text)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Code in %d Blöcken, erwartet eine Aufteilung", len(chunks))
+	}
+	if got := strings.Join(chunks, "\n"); got != code {
+		t.Fatal("aufgeteilter Code ergibt zusammengesetzt nicht das Original")
+	}
+	for _, block := range []Block{blocks[0], blocks[len(blocks)-1]} {
+		if block.Type != "section" || strings.Contains(block.Text.Text, "```") {
+			t.Fatalf("Text um den Code: %s %v", block.Type, block.Text)
+		}
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{"passt", "abc", 5, []string{"abc"}},
+		{"leer", "", 5, []string{""}},
+		{"an leerzeichen", "eins zwei drei", 10, []string{"eins zwei", "drei"}},
+		{"zeilenumbruch zuerst", "eins\nzwei drei", 10, []string{"eins", "zwei drei"}},
+		{"ohne trennzeichen", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"zeichen statt bytes", "äöüßäöü", 3, []string{"äöü", "ßäö", "ü"}},
+		{"einrückung bleibt", "  code\n  mehr", 8, []string{"  code", "  mehr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("%q ergibt %q, erwartet %q", tt.text, got, tt.want)
+			}
+			for _, chunk := range got {
+				if !utf8.ValidString(chunk) || utf8.RuneCountInString(chunk) > tt.max {
+					t.Fatalf("Stück %q über %d Zeichen", chunk, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownMessages(t *testing.T) {
+	paragraph := strings.Repeat("x", 2900)
+	tests := []struct {
+		name     string
+		markdown string
+		messages int
+	}{
+		{"leer", "", 1},
+		{"kurz", "**Hallo**", 1},
+		{"blocklimit", strings.Repeat("Absatz\n---\n", 60), 3},
+		{"textlimit", strings.Repeat(paragraph+"\n---\n", 14), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := MarkdownMessages("U123", "C123", tt.markdown)
+			if len(messages) != tt.messages {
+				t.Fatalf("%d Nachrichten, erwartet %d", len(messages), tt.messages)
+			}
+			blocks := 0
+			for _, message := range messages {
+				if message.User != "U123" || message.Channel != "C123" {
+					t.Fatalf("Nachricht an %s/%s", message.User, message.Channel)
+				}
+				length := 0
+				for _, block := range message.Blocks {
+					length += blockLength(block)
+				}
+				if len(message.Blocks) == 0 || len(message.Blocks) > MaxMessageBlocks || length > maxMessageLength {
+					t.Fatalf("Nachricht mit %d Blöcken und %d Zeichen", len(message.Blocks), length)
+				}
+				if err := ValidateBlocks(message.Blocks, MaxMessageBlocks); err != nil {
+					t.Fatal(err)
+				}
+				blocks += len(message.Blocks)
+			}
+			if want := len(MarkdownBlocks(tt.markdown)); tt.markdown != "" && blocks != want {
+				t.Fatalf("%d Blöcke verteilt, erwartet %d", blocks, want)
+			}
+		})
+	}
+}
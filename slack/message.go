@@ -1,13 +1,16 @@
 package slack
 
 type Message struct {
-	Channel     string       `json:"channel,omitempty"`
-	TimeStamp   string       `json:"ts,omitempty"`
-	User        string       `json:"user,omitempty"`
-	Text        string       `json:"text,omitempty"`
-	Blocks      []Block      `json:"blocks,omitempty"`
-	Attachments []Attachment `json:"attachments,omitempty"`
-	Color       string       `json:"color"`
+	Channel   string `json:"channel,omitempty"`
+	TimeStamp string `json:"ts,omitempty"`
+	// ThreadTs ist der ts der Ursprungsnachricht, wenn die Nachricht im Thread erscheinen soll.
+	ThreadTs       string       `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool         `json:"reply_broadcast,omitempty"`
+	User           string       `json:"user,omitempty"`
+	Text           string       `json:"text,omitempty"`
+	Blocks         []Block      `json:"blocks,omitempty"`
+	Attachments    []Attachment `json:"attachments,omitempty"`
+	Color          string       `json:"color"`
 }
 
 type Command struct {
@@ -38,6 +41,7 @@ type Input struct {
 
 type Payload struct {
 	Token               string          `json:"token"`
+	Challenge           string          `json:"challenge,omitempty"`
 	TeamID              string          `json:"team_id"`
 	ContextTeamID       string          `json:"context_team_id"`
 	ContextEnterpriseID *string         `json:"context_enterprise_id"`
@@ -62,6 +66,7 @@ type MessageDetail struct {
 	BotProfile BotProfile `json:"bot_profile"`
 	Edited     Edited     `json:"edited"`
 	Ts         string     `json:"ts"`
+	ThreadTs   string     `json:"thread_ts,omitempty"`
 	SourceTeam string     `json:"source_team"`
 	UserTeam   string     `json:"user_team"`
 }
@@ -85,35 +90,36 @@ type Authorization struct {
 	IsEnterpriseInstall bool    `json:"is_enterprise_install"`
 }
 
-type Event struct {
-	Type            string        `json:"type"`
-	Subtype         string        `json:"subtype"`
-	Message         MessageDetail `json:"message"`
-	PreviousMessage MessageDetail `json:"previous_message"`
-	Channel         string        `json:"channel"`
-	Hidden          bool          `json:"hidden"`
-	EventTs         string        `json:"event_ts"`
-	ChannelType     string        `json:"channel_type"`
-	Token           string        `json:"token"`
-	TeamID          string        `json:"team_id"`
-	TeamDomain      string        `json:"team_domain"`
-	ChannelName     string        `json:"channel_name"`
-	User            string        `json:"user"`
-	UserName        string        `json:"user_name"`
-	Commands        string        `json:"commands"`
-	Text            string        `json:"text"`
-	Timestamp       string        `json:"ts,omitempty"`
-}
-
-type Block struct {
-	Type   string `json:"type"`
-	Text   *Text  `json:"text,omitempty"`
-	Fields []Text `json:"fields,omitempty"`
+// RevokedTokens listet bei tokens_revoked die widerrufenen Tokens nach User-IDs.
+type RevokedTokens struct {
+	OAuth []string `json:"oauth"`
+	Bot   []string `json:"bot"`
 }
 
-type Text struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+type Event struct {
+	Type            string         `json:"type"`
+	Subtype         string         `json:"subtype"`
+	Message         MessageDetail  `json:"message"`
+	PreviousMessage MessageDetail  `json:"previous_message"`
+	Channel         string         `json:"channel"`
+	Hidden          bool           `json:"hidden"`
+	EventTs         string         `json:"event_ts"`
+	ChannelType     string         `json:"channel_type"`
+	Token           string         `json:"token"`
+	TeamID          string         `json:"team_id"`
+	TeamDomain      string         `json:"team_domain"`
+	ChannelName     string         `json:"channel_name"`
+	User            string         `json:"user"`
+	UserName        string         `json:"user_name"`
+	Commands        string         `json:"commands"`
+	Text            string         `json:"text"`
+	Timestamp       string         `json:"ts,omitempty"`
+	ThreadTs        string         `json:"thread_ts,omitempty"`
+	ParentUserID    string         `json:"parent_user_id,omitempty"`
+	Tab             string         `json:"tab,omitempty"`
+	Tokens          *RevokedTokens `json:"tokens,omitempty"`
+	BotID           string         `json:"bot_id,omitempty"`
+	BotProfile      *BotProfile    `json:"bot_profile,omitempty"`
 }
 
 func GetSimpleMessage(user string, channel string, message string) Message {
@@ -131,3 +137,10 @@ func GetSimpleMessage(user string, channel string, message string) Message {
 		},
 	}
 }
+
+// InThread liefert die Nachricht als Antwort im Thread von threadTs. Ein leerer threadTs lässt
+// sie im Kanal.
+func (m Message) InThread(threadTs string) Message {
+	m.ThreadTs = threadTs
+	return m
+}
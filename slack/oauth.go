@@ -0,0 +1,285 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-slack-ics/system"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultScopes sind die Bot-Scopes, die /slack/install anfragt, wenn SLACK_SCOPES fehlt.
+const DefaultScopes = "app_mentions:read,channels:history,chat:write,commands,files:write,groups:history,im:history,users:read"
+
+// Installation ist die Installation der App in einem Workspace.
+type Installation struct {
+	TeamID       string    `json:"team_id"`
+	TeamName     string    `json:"team_name"`
+	EnterpriseID string    `json:"enterprise_id,omitempty"`
+	AppID        string    `json:"app_id"`
+	BotUserID    string    `json:"bot_user_id"`
+	BotToken     string    `json:"bot_token"`
+	Scope        string    `json:"scope"`
+	InstalledBy  string    `json:"installed_by"`
+	InstalledAt  time.Time `json:"installed_at"`
+}
+
+// InstallationStore speichert die Bot-Tokens pro team_id.
+type InstallationStore interface {
+	Save(installation Installation) error
+	Load(teamID string) (Installation, error)
+	// LoadByBotUser findet die Installation zu einer Bot-User-ID, z. B. für tokens_revoked.
+	LoadByBotUser(botUserID string) (Installation, error)
+	Delete(teamID string) error
+	// Teams liefert die team_ids aller Installationen.
+	Teams() ([]string, error)
+}
+
+// Installations liefert die Tokens für WithTeam. Ohne Installation für das Team wird
+// SLACK_TOKEN verwendet, so bleibt die bisherige Einzel-Installation nutzbar.
+var Installations InstallationStore = RedisInstallationStore{}
+
+// RedisInstallationStore legt jede Installation unter slack:installation:<team_id> ab und
+// verweist per Alias von der Bot-User-ID darauf.
+type RedisInstallationStore struct{}
+
+func installationKey(teamID string) string {
+	return "slack:installation:" + teamID
+}
+
+func botUserKey(botUserID string) string {
+	return "slack:installation:bot:" + botUserID
+}
+
+func (RedisInstallationStore) Save(installation Installation) error {
+	defer forgetToken(installation.TeamID)
+	return system.RedisInstance().Set(installationKey(installation.TeamID), installation, botUserKey(installation.BotUserID))
+}
+
+func (RedisInstallationStore) Load(teamID string) (Installation, error) {
+	var installation Installation
+	err := system.RedisInstance().Get(installationKey(teamID), "", &installation)
+	return installation, err
+}
+
+func (RedisInstallationStore) LoadByBotUser(botUserID string) (Installation, error) {
+	var installation Installation
+	err := system.RedisInstance().Get("", botUserKey(botUserID), &installation)
+	return installation, err
+}
+
+func (s RedisInstallationStore) Delete(teamID string) error {
+	defer forgetToken(teamID)
+	redis := system.RedisInstance()
+	if installation, err := s.Load(teamID); err == nil && installation.BotUserID != "" {
+		redis.Del(botUserKey(installation.BotUserID))
+	}
+	return redis.Del(installationKey(teamID))
+}
+
+func (RedisInstallationStore) Teams() ([]string, error) {
+	keys, err := system.RedisInstance().Scan(installationKey("*"))
+	if err != nil {
+		return nil, err
+	}
+	var teams []string
+	for _, key := range keys {
+		if teamID := strings.TrimPrefix(key, installationKey("")); !strings.HasPrefix(teamID, "bot:") {
+			teams = append(teams, teamID)
+		}
+	}
+	return teams, nil
+}
+
+type teamKey struct{}
+
+// WithTeam legt fest, in welchem Workspace die Aufrufe mit diesem Context landen.
+func WithTeam(ctx context.Context, teamID string) context.Context {
+	if teamID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, teamKey{}, teamID)
+}
+
+// TeamFromContext liefert die team_id aus WithTeam.
+func TeamFromContext(ctx context.Context) string {
+	teamID, _ := ctx.Value(teamKey{}).(string)
+	return teamID
+}
+
+// tokenCacheTTL begrenzt, wie lange token ein Bot-Token aus dem Speicher nimmt. Save, Delete und
+// HandleUninstall leeren den Eintrag sofort, die TTL deckt Änderungen anderer Instanzen ab.
+const tokenCacheTTL = 5 * time.Minute
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+var (
+	tokenMu sync.Mutex
+	// tokens hält das Bot-Token je team_id, "" steht für ein Team ohne Installation
+	tokens = make(map[string]cachedToken)
+)
+
+// installationToken liefert das Bot-Token der Installation des Teams oder "". Es wird pro Team
+// zwischengespeichert, damit nicht jeder API-Aufruf Redis liest.
+func installationToken(teamID string) string {
+	tokenMu.Lock()
+	cached, ok := tokens[teamID]
+	tokenMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.token
+	}
+
+	installation, err := Installations.Load(teamID)
+	if err != nil && !errors.Is(err, system.ErrNil) {
+		// Redis ist nicht erreichbar, nicht als "keine Installation" merken
+		return ""
+	}
+
+	tokenMu.Lock()
+	tokens[teamID] = cachedToken{token: installation.BotToken, expires: time.Now().Add(tokenCacheTTL)}
+	tokenMu.Unlock()
+	return installation.BotToken
+}
+
+// forgetToken entfernt das zwischengespeicherte Token des Teams.
+func forgetToken(teamID string) {
+	tokenMu.Lock()
+	delete(tokens, teamID)
+	tokenMu.Unlock()
+}
+
+// token liefert das Bot-Token für den Aufruf: Slack.Token, das Token der Installation des Teams
+// aus dem Context oder SLACK_TOKEN.
+func (s *Slack) token(ctx context.Context) string {
+	if s.Token != "" {
+		return s.Token
+	}
+	if teamID := TeamFromContext(ctx); teamID != "" && Installations != nil {
+		if token := installationToken(teamID); token != "" {
+			return token
+		}
+	}
+	return os.Getenv("SLACK_TOKEN")
+}
+
+// ForTeam liefert einen Client, der fest mit dem Token des Teams arbeitet.
+func ForTeam(teamID string) (*Slack, error) {
+	installation, err := Installations.Load(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("keine Installation für %s: %w", teamID, err)
+	}
+	return &Slack{BaseURL: Instance.BaseURL, Token: installation.BotToken}, nil
+}
+
+// InstallURL ist die Adresse von "Add to Slack" für die Scopes und den state.
+func InstallURL(clientID, scopes, redirectURI, state string) string {
+	query := url.Values{}
+	query.Set("client_id", clientID)
+	query.Set("scope", scopes)
+	query.Set("state", state)
+	if redirectURI != "" {
+		query.Set("redirect_uri", redirectURI)
+	}
+	return "https://slack.com/oauth/v2/authorize?" + query.Encode()
+}
+
+// ExchangeCode tauscht den Code aus dem OAuth-Redirect gegen das Bot-Token.
+func (s *Slack) ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI string) (Installation, error) {
+	form := url.Values{}
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	form.Set("code", code)
+	if redirectURI != "" {
+		form.Set("redirect_uri", redirectURI)
+	}
+
+	body, err := DefaultClient.Do(ctx, Request{
+		Method:      "oauth.v2.access",
+		URL:         s.apiURL("oauth.v2.access"),
+		ContentType: "application/x-www-form-urlencoded",
+		Body:        []byte(form.Encode()),
+	})
+	if err != nil {
+		return Installation{}, err
+	}
+
+	var response struct {
+		Ok          bool   `json:"ok"`
+		Error       string `json:"error"`
+		AccessToken string `json:"access_token"`
+		Scope       string `json:"scope"`
+		BotUserID   string `json:"bot_user_id"`
+		AppID       string `json:"app_id"`
+		Team        struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"team"`
+		Enterprise *struct {
+			ID string `json:"id"`
+		} `json:"enterprise"`
+		AuthedUser struct {
+			ID string `json:"id"`
+		} `json:"authed_user"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Installation{}, fmt.Errorf("slack oauth.v2.access: ungültige Antwort: %w", err)
+	}
+	if !response.Ok {
+		return Installation{}, &SlackError{Method: "oauth.v2.access", Code: response.Error}
+	}
+
+	installation := Installation{
+		TeamID:      response.Team.ID,
+		TeamName:    response.Team.Name,
+		AppID:       response.AppID,
+		BotUserID:   response.BotUserID,
+		BotToken:    response.AccessToken,
+		Scope:       response.Scope,
+		InstalledBy: response.AuthedUser.ID,
+		InstalledAt: time.Now(),
+	}
+	if response.Enterprise != nil {
+		installation.EnterpriseID = response.Enterprise.ID
+	}
+	return installation, nil
+}
+
+// HandleUninstall entfernt Installationen nach app_uninstalled bzw. tokens_revoked.
+func HandleUninstall(payload Payload) {
+	switch payload.Event.Type {
+	case "app_uninstalled":
+		err := Installations.Delete(payload.TeamID)
+		forgetToken(payload.TeamID)
+		if err != nil {
+			log.Printf("Fehler beim Entfernen der Installation %s: %v", payload.TeamID, err)
+			return
+		}
+		log.Printf("App aus Workspace %s entfernt", payload.TeamID)
+	case "tokens_revoked":
+		if payload.Event.Tokens == nil {
+			return
+		}
+		for _, botUserID := range payload.Event.Tokens.Bot {
+			installation, err := Installations.LoadByBotUser(botUserID)
+			if err != nil {
+				continue
+			}
+			err = Installations.Delete(installation.TeamID)
+			forgetToken(installation.TeamID)
+			if err != nil {
+				log.Printf("Fehler beim Entfernen der Installation %s: %v", installation.TeamID, err)
+				continue
+			}
+			log.Printf("Bot-Token für Workspace %s widerrufen", installation.TeamID)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"go-slack-ics/system"
+	"sync"
+	"testing"
+)
+
+// memoryInstallations ist ein InstallationStore im Speicher, der die Aufrufe von Load zählt.
+type memoryInstallations struct {
+	mu            sync.Mutex
+	installations map[string]Installation
+	loads         int
+	err           error
+}
+
+func (m *memoryInstallations) Save(installation Installation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.installations[installation.TeamID] = installation
+	return nil
+}
+
+func (m *memoryInstallations) Load(teamID string) (Installation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	if m.err != nil {
+		return Installation{}, m.err
+	}
+	installation, ok := m.installations[teamID]
+	if !ok {
+		return Installation{}, system.ErrNil
+	}
+	return installation, nil
+}
+
+func (m *memoryInstallations) LoadByBotUser(botUserID string) (Installation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, installation := range m.installations {
+		if installation.BotUserID == botUserID {
+			return installation, nil
+		}
+	}
+	return Installation{}, system.ErrNil
+}
+
+func (m *memoryInstallations) Delete(teamID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.installations, teamID)
+	return nil
+}
+
+func (m *memoryInstallations) Teams() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var teams []string
+	for teamID := range m.installations {
+		teams = append(teams, teamID)
+	}
+	return teams, nil
+}
+
+func (m *memoryInstallations) loadCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loads
+}
+
+// useInstallations setzt store als Installations und leert den Token-Cache.
+func useInstallations(t *testing.T, installations ...Installation) *memoryInstallations {
+	store := &memoryInstallations{installations: make(map[string]Installation)}
+	for _, installation := range installations {
+		store.installations[installation.TeamID] = installation
+	}
+	saved := Installations
+	Installations = store
+	reset := func() {
+		tokenMu.Lock()
+		tokens = make(map[string]cachedToken)
+		tokenMu.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		Installations = saved
+		reset()
+	})
+	t.Setenv("SLACK_TOKEN", "xoxb-default")
+	return store
+}
+
+func TestTokenCached(t *testing.T) {
+	store := useInstallations(t, Installation{TeamID: "T1", BotUserID: "B1", BotToken: "xoxb-T1"})
+	s := &Slack{}
+
+	for i := 0; i < 3; i++ {
+		if token := s.token(WithTeam(context.Background(), "T1")); token != "xoxb-T1" {
+			t.Fatalf("Token %s, erwartet xoxb-T1", token)
+		}
+		// Der Workspace von SLACK_TOKEN hat keine Installation, auch das wird gemerkt
+		if token := s.token(WithTeam(context.Background(), "T2")); token != "xoxb-default" {
+			t.Fatalf("Token %s, erwartet SLACK_TOKEN", token)
+		}
+	}
+	if n := store.loadCount(); n != 2 {
+		t.Fatalf("%d Aufrufe von Load, erwartet 2", n)
+	}
+}
+
+func TestTokenNotCachedOnError(t *testing.T) {
+	store := useInstallations(t)
+	store.err = errors.New("redis nicht erreichbar")
+	s := &Slack{}
+
+	for i := 0; i < 2; i++ {
+		if token := s.token(WithTeam(context.Background(), "T1")); token != "xoxb-default" {
+			t.Fatalf("Token %s, erwartet SLACK_TOKEN", token)
+		}
+	}
+	if n := store.loadCount(); n != 2 {
+		t.Fatalf("%d Aufrufe von Load, ein Fehler darf nicht im Cache landen", n)
+	}
+}
+
+func TestTokenForgottenOnUninstall(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload Payload
+	}{
+		{"app_uninstalled", Payload{TeamID: "T1", Event: Event{Type: "app_uninstalled"}}},
+		{"tokens_revoked", Payload{TeamID: "T1", Event: Event{Type: "tokens_revoked", Tokens: &RevokedTokens{Bot: []string{"B1"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useInstallations(t, Installation{TeamID: "T1", BotUserID: "B1", BotToken: "xoxb-T1"})
+			ctx := WithTeam(context.Background(), "T1")
+			s := &Slack{}
+			if token := s.token(ctx); token != "xoxb-T1" {
+				t.Fatalf("Token %s vor der Deinstallation", token)
+			}
+
+			HandleUninstall(tt.payload)
+			if token := s.token(ctx); token != "xoxb-default" {
+				t.Fatalf("Token %s nach der Deinstallation, erwartet SLACK_TOKEN", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package slack
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Slack nimmt an einer response_url höchstens fünf Antworten innerhalb von 30 Minuten an.
+const (
+	ResponseURLLifetime = 30 * time.Minute
+	MaxResponses        = 5
+)
+
+// Responder schickt verzögerte Antworten auf einen Slash-Befehl an dessen response_url und hält
+// dabei die Grenzen von Slack ein. Die letzte mögliche Antwort bleibt für Final reserviert.
+type Responder struct {
+	URL string
+
+	mu     sync.Mutex
+	issued time.Time
+	sent   int
+}
+
+// NewResponder beginnt die 30 Minuten ab jetzt, also beim Eingang des Befehls.
+func NewResponder(responseURL string) *Responder {
+	return &Responder{URL: responseURL, issued: time.Now()}
+}
+
+// Responder liefert den Responder für die response_url des Befehls.
+func (c Command) Responder() *Responder {
+	return NewResponder(c.ResponseURL)
+}
+
+// Remaining ist die Zahl der Antworten, die noch möglich sind.
+func (r *Responder) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remaining()
+}
+
+func (r *Responder) remaining() int {
+	if r.URL == "" || time.Since(r.issued) >= ResponseURLLifetime {
+		return 0
+	}
+	return MaxResponses - r.sent
+}
+
+// Send schickt eine Antwort, solange die response_url noch gültig ist. Fehlgeschlagene Versuche
+// zählen mit, weil Slack sie nicht zuverlässig zurückweist.
+func (r *Responder) Send(ctx context.Context, message ResponseMessage) error {
+	if err := r.reserve(); err != nil {
+		return err
+	}
+	return PostResponse(ctx, r.URL, message)
+}
+
+func (r *Responder) reserve() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.URL == "":
+		return &SlackError{Method: "response_url", Code: "no_response_url"}
+	case time.Since(r.issued) >= ResponseURLLifetime:
+		return &SlackError{Method: "response_url", Code: "expired_url"}
+	case r.sent >= MaxResponses:
+		return &SlackError{Method: "response_url", Code: "used_url"}
+	}
+	r.sent++
+	return nil
+}
+
+// Progress ersetzt die bisherige ephemere Antwort durch einen Zwischenstand. Ist nur noch eine
+// Antwort übrig, wird der Zwischenstand ausgelassen, damit Final sie nutzen kann.
+func (r *Responder) Progress(ctx context.Context, text string) error {
+	r.mu.Lock()
+	if r.remaining() <= 1 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.sent++
+	r.mu.Unlock()
+	return PostResponse(ctx, r.URL, ephemeral(text))
+}
+
+// Final ersetzt die bisherige ephemere Antwort durch das Ergebnis.
+func (r *Responder) Final(ctx context.Context, text string) error {
+	return r.Send(ctx, ephemeral(text))
+}
+
+func ephemeral(text string) ResponseMessage {
+	return ResponseMessage{
+		Message:         Message{Text: text},
+		ResponseType:    "ephemeral",
+		ReplaceOriginal: true,
+	}
+}
@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// responseServer nimmt Antworten an einer response_url an und merkt sich die Payloads.
+type responseServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	received []ResponseMessage
+}
+
+func newResponseServer(t *testing.T) *responseServer {
+	s := &responseServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var message ResponseMessage
+		json.Unmarshal(body, &message)
+		s.mu.Lock()
+		s.received = append(s.received, message)
+		s.mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(s.Close)
+
+	// Ein eigener Client ohne Rate-Limit für response_url
+	saved := DefaultClient
+	DefaultClient = testClient()
+	DefaultClient.Limits = map[string]int{"response_url": 6000}
+	t.Cleanup(func() { DefaultClient = saved })
+	return s
+}
+
+func (s *responseServer) messages() []ResponseMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ResponseMessage(nil), s.received...)
+}
+
+// slackCode liefert den Code eines *SlackError oder "".
+func slackCode(err error) string {
+	var slackErr *SlackError
+	if errors.As(err, &slackErr) {
+		return slackErr.Code
+	}
+	return ""
+}
+
+func TestResponderUsedURL(t *testing.T) {
+	server := newResponseServer(t)
+	r := NewResponder(server.URL)
+
+	for i := 0; i < MaxResponses; i++ {
+		if err := r.Send(context.Background(), EphemeralResponse("Antwort")); err != nil {
+			t.Fatalf("Antwort %d: %v", i+1, err)
+		}
+	}
+	if err := r.Send(context.Background(), EphemeralResponse("zu viel")); slackCode(err) != "used_url" {
+		t.Fatalf("sechste Antwort: %v, erwartet used_url", err)
+	}
+	if n := len(server.messages()); n != MaxResponses {
+		t.Fatalf("%d Antworten bei Slack, erwartet %d", n, MaxResponses)
+	}
+	if n := r.Remaining(); n != 0 {
+		t.Fatalf("noch %d Antworten übrig", n)
+	}
+}
+
+func TestResponderProgressLeavesLastForFinal(t *testing.T) {
+	server := newResponseServer(t)
+	r := NewResponder(server.URL)
+
+	for i := 0; i < MaxResponses+2; i++ {
+		if err := r.Progress(context.Background(), "läuft"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(server.messages()); n != MaxResponses-1 {
+		t.Fatalf("%d Zwischenstände bei Slack, erwartet %d", n, MaxResponses-1)
+	}
+	if n := r.Remaining(); n != 1 {
+		t.Fatalf("%d Antworten übrig, erwartet 1 für Final", n)
+	}
+
+	if err := r.Final(context.Background(), "fertig"); err != nil {
+		t.Fatal(err)
+	}
+	messages := server.messages()
+	last := messages[len(messages)-1]
+	if len(messages) != MaxResponses || last.Text != "fertig" || !last.ReplaceOriginal || last.ResponseType != "ephemeral" {
+		t.Fatalf("%d Antworten, zuletzt %+v", len(messages), last)
+	}
+	if err := r.Final(context.Background(), "noch mal"); slackCode(err) != "used_url" {
+		t.Fatalf("Final nach dem Limit: %v, erwartet used_url", err)
+	}
+}
+
+func TestResponderExpiredURL(t *testing.T) {
+	server := newResponseServer(t)
+	r := NewResponder(server.URL)
+	r.issued = time.Now().Add(-ResponseURLLifetime)
+
+	if err := r.Progress(context.Background(), "läuft"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Final(context.Background(), "zu spät"); slackCode(err) != "expired_url" {
+		t.Fatalf("Final: %v, erwartet expired_url", err)
+	}
+	if n := len(server.messages()); n != 0 {
+		t.Fatalf("%d Antworten an eine abgelaufene response_url", n)
+	}
+	if n := r.Remaining(); n != 0 {
+		t.Fatalf("noch %d Antworten übrig", n)
+	}
+}
+
+func TestResponderWithoutURL(t *testing.T) {
+	r := Command{}.Responder()
+	if err := r.Final(context.Background(), "nirgendwohin"); slackCode(err) != "no_response_url" {
+		t.Fatalf("Final: %v, erwartet no_response_url", err)
+	}
+}
@@ -17,14 +17,51 @@ type BotProfile struct {
 }
 
 type ResponseMetadata struct {
-	Warnings []string `json:"warnings"`
+	Warnings   []string `json:"warnings"`
+	NextCursor string   `json:"next_cursor,omitempty"`
 }
 
 type Response struct {
-	Ok               bool             `json:"ok"`
-	Channel          string           `json:"channel"`
-	Ts               string           `json:"ts"`
-	Message          Message          `json:"message"`
-	Warning          string           `json:"warning"`
+	Ok      bool           `json:"ok"`
+	Error   string         `json:"error,omitempty"`
+	Channel string         `json:"channel"`
+	Ts      string         `json:"ts"`
+	Message Message        `json:"message"`
+	Files   []UploadedFile `json:"files,omitempty"`
+	// ScheduledMessageID und PostAt liefert chat.scheduleMessage.
+	ScheduledMessageID string           `json:"scheduled_message_id,omitempty"`
+	PostAt             int64            `json:"post_at,omitempty"`
+	Warning            string           `json:"warning"`
+	ResponseMetadata   ResponseMetadata `json:"response_metadata"`
+}
+
+type UploadURLResponse struct {
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+type UploadedFile struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Permalink string `json:"permalink"`
+}
+
+// Member ist ein Eintrag aus users.list.
+type Member struct {
+	ID      string `json:"id"`
+	TeamID  string `json:"team_id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+	IsBot   bool   `json:"is_bot"`
+	TZ      string `json:"tz"`
+	Locale  string `json:"locale"`
+	Profile struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type UsersListResponse struct {
+	Members          []Member         `json:"members"`
 	ResponseMetadata ResponseMetadata `json:"response_metadata"`
 }
@@ -1,206 +1,208 @@
 package slack
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
-	"mime/multipart"
-	"net/http"
+	"net/url"
 	"os"
-
-	"github.com/apognu/gocal"
+	"strings"
+	"time"
 )
 
+const defaultAPIURL = "https://slack.com/api/"
+
 type Slack struct {
 	Message string
 	User    string
+	// BaseURL überschreibt die Slack-API, z. B. für den Fake-Server aus slack/slacktest.
+	// Ohne Angabe wird SLACK_API_URL bzw. https://slack.com/api/ verwendet.
+	BaseURL string
+	// Token überschreibt das Bot-Token, siehe token.
+	Token string
 }
 
-func (s *Slack) toJSON(v interface{}) string {
-	marshal, err := json.Marshal(v)
-	if err != nil {
-		log.Fatalf("Fehler beim Umwandeln in JSON: %v", err)
+func (s *Slack) apiURL(method string) string {
+	base := s.BaseURL
+	if base == "" {
+		base = os.Getenv("SLACK_API_URL")
 	}
-	return string(marshal)
+	if base == "" {
+		base = defaultAPIURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + method
 }
 
-func (s *Slack) SendCalenderEvent(e gocal.Event, user string) {
+func (s *Slack) SendBlocks(ctx context.Context, channel string, text string, blocks []Block) (Response, error) {
 	msg := Message{
-		Channel: user,
-		Blocks: []Block{
-			{
-				Type: "header",
-				Text: &Text{
-					Type: "plain_text",
-					Text: e.Start.Format("02.01.2006") + " » " + e.Summary,
-				},
-			},
-			{
-				Type: "section",
-				Text: &Text{
-					Type: "mrkdwn",
-					Text: e.Description,
-				},
-			},
-		},
+		Channel: channel,
+		Text:    text,
+		Blocks:  blocks,
 	}
 
-	payload := s.toJSON(msg)
-	s.PostMessage([]byte(payload))
+	return s.call(ctx, "chat.postMessage", msg)
 }
 
-func (s *Slack) PostMessage(payload []byte) Response {
-	url := "https://slack.com/api/chat.postMessage"
-	return s.sendPayload(url, payload)
+func (s *Slack) PostMessage(ctx context.Context, payload []byte) (Response, error) {
+	return s.sendPayload(ctx, "chat.postMessage", payload)
 }
 
-func (s *Slack) changeMessage(payload []byte) Response {
-	url := "https://slack.com/api/chat.update"
-	return s.sendPayload(url, payload)
-}
-
-func (s *Slack) sendPayload(url string, payload []byte) Response {
-	token := os.Getenv("SLACK_TOKEN")
-
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	if err != nil {
-		log.Fatalf("Fehler beim Erstellen der Anforderung: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := client.Do(req)
+// call serialisiert v als JSON und ruft die Methode auf.
+func (s *Slack) call(ctx context.Context, method string, v interface{}) (Response, error) {
+	payload, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("Fehler beim Senden der Anforderung: %v", err)
+		return Response{}, fmt.Errorf("Fehler beim Umwandeln in JSON: %w", err)
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-
-		}
-	}(resp.Body)
+	return s.sendPayload(ctx, method, payload)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// sendPayload schickt den JSON-Payload an die Methode. Antwortet Slack mit "ok": false,
+// kommt die Antwort zusammen mit einem *SlackError zurück.
+func (s *Slack) sendPayload(ctx context.Context, method string, payload []byte) (Response, error) {
+	// Der Kanal bestimmt die Warteschlange und das Rate-Limit von chat.postMessage
+	var target struct {
+		Channel string `json:"channel"`
+	}
+	json.Unmarshal(payload, &target)
+
+	body, err := DefaultClient.Do(ctx, Request{
+		Method:      method,
+		URL:         s.apiURL(method),
+		Channel:     target.Channel,
+		ContentType: "application/json; charset=utf-8",
+		Token:       s.token(ctx),
+		Body:        payload,
+	})
 	if err != nil {
-		log.Fatalf("Fehler beim Lesen der Antwort: %v", err)
+		return Response{}, err
 	}
 
 	var response Response
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		log.Printf("response error: %s", err.Error())
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Response{}, fmt.Errorf("slack %s: ungültige Antwort: %w", method, err)
+	}
+	if !response.Ok {
+		return response, &SlackError{Method: method, Code: response.Error}
 	}
 
-	return response
+	return response, nil
 }
 
-func (s *Slack) Send() {
+func (s *Slack) Send(ctx context.Context) (Response, error) {
 	o := make(map[string]string)
 
 	o["text"] = s.Message
 	o["channel"] = s.User
-	payload := s.toJSON(o)
-	s.PostMessage([]byte(payload))
+	return s.call(ctx, "chat.postMessage", o)
 }
 
-func (s *Slack) SendMessage(channel string, user string, message Message) Response {
+func (s *Slack) SendMessage(ctx context.Context, channel string, user string, message Message) (Response, error) {
 	message.User = user
 	message.Channel = channel
-	payload := s.toJSON(message)
-	response := s.PostMessage([]byte(payload))
-
-	return response
+	return s.call(ctx, "chat.postMessage", message)
 }
 
-func (s *Slack) ChangeMessage(ts string, channel string, user string, message Message) Response {
+func (s *Slack) ChangeMessage(ctx context.Context, ts string, channel string, user string, message Message) (Response, error) {
 	message.User = user
 	message.Channel = channel
 	message.TimeStamp = ts
-	payload := s.toJSON(message)
-	response := s.changeMessage([]byte(payload))
-
-	return response
+	// chat.update kennt kein thread_ts, die Nachricht bleibt, wo sie ist
+	message.ThreadTs = ""
+	return s.call(ctx, "chat.update", message)
 }
 
-func (s *Slack) SendImageToSlack(fileBytes []byte, fileName, message, channel string) error {
-	token := os.Getenv("SLACK_TOKEN")
+// ScheduleMessage lässt Slack die Nachricht zum Zeitpunkt postAt verschicken, auch wenn dieser
+// Dienst dann nicht läuft. Response.ScheduledMessageID und Response.Channel werden zum Löschen
+// gebraucht.
+func (s *Slack) ScheduleMessage(ctx context.Context, channel string, postAt time.Time, text string, blocks []Block) (Response, error) {
+	return s.call(ctx, "chat.scheduleMessage", map[string]interface{}{
+		"channel": channel,
+		"post_at": postAt.Unix(),
+		"text":    text,
+		"blocks":  blocks,
+	})
+}
 
-	// Erstelle einen Buffer und einen multipart writer
-	var requestBody bytes.Buffer
-	multipartWriter := multipart.NewWriter(&requestBody)
+// DeleteScheduledMessage löscht eine geplante Nachricht, bevor sie verschickt wird.
+func (s *Slack) DeleteScheduledMessage(ctx context.Context, channel string, id string) error {
+	_, err := s.call(ctx, "chat.deleteScheduledMessage", map[string]string{
+		"channel":              channel,
+		"scheduled_message_id": id,
+	})
+	return err
+}
 
-	// Füge die Datei hinzu. Ersetze 'file' durch einen geeigneten Dateinamen.
-	filePart, err := multipartWriter.CreateFormFile("file", fileName)
+// OpenConnection holt mit dem App-Level-Token (xapp-…) eine WebSocket-URL für Socket Mode.
+// Jede URL gilt nur für eine Verbindung.
+func (s *Slack) OpenConnection(ctx context.Context, appToken string) (string, error) {
+	body, err := DefaultClient.Do(ctx, Request{
+		Method:      "apps.connections.open",
+		URL:         s.apiURL("apps.connections.open"),
+		ContentType: "application/x-www-form-urlencoded",
+		Token:       appToken,
+	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Schreibe die Byte-Daten in das filePart
-	if _, err := filePart.Write(fileBytes); err != nil {
-		return err
+	var response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		URL   string `json:"url"`
 	}
-
-	// Füge die anderen Felder hinzu
-	if err := multipartWriter.WriteField("initial_comment", message); err != nil {
-		return err
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("slack apps.connections.open: ungültige Antwort: %w", err)
 	}
-	if err := multipartWriter.WriteField("channels", channel); err != nil {
-		return err
-	}
-
-	// Wichtig: Schließe den multipart writer, um das Ende des Formulars zu signalisieren
-	if err := multipartWriter.Close(); err != nil {
-		return err
-	}
-
-	// Erstelle die Anfrage
-	req, err := http.NewRequest("POST", "https://slack.com/api/files.upload", &requestBody)
-	if err != nil {
-		return err
+	if !response.Ok {
+		return "", &SlackError{Method: "apps.connections.open", Code: response.Error}
 	}
+	return response.URL, nil
+}
 
-	// Setze den Authorization Header und Content-Type
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+// PublishView veröffentlicht den App-Home-Tab eines Users.
+func (s *Slack) PublishView(ctx context.Context, userID string, view View) (Response, error) {
+	return s.call(ctx, "views.publish", map[string]interface{}{
+		"user_id": userID,
+		"view":    view,
+	})
+}
 
-	// Sende die Anfrage
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// ListUsers liefert eine Seite aus users.list und den Cursor der nächsten Seite ("" am Ende).
+func (s *Slack) ListUsers(ctx context.Context, cursor string) ([]Member, string, error) {
+	form := url.Values{}
+	form.Set("limit", "200")
+	form.Set("include_locale", "true")
+	if cursor != "" {
+		form.Set("cursor", cursor)
 	}
-	defer resp.Body.Close()
 
-	// Überprüfe den Status der Antwort
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Fehler beim Hochladen der Datei: %s", resp.Status)
+	var response UsersListResponse
+	if err := s.postForm(ctx, "users.list", "", form, &response); err != nil {
+		return nil, "", err
 	}
+	return response.Members, response.ResponseMetadata.NextCursor, nil
+}
 
-	// Optional: Antwort von Slack verarbeiten
-
-	return nil
+func (s *Slack) SendImageToSlack(ctx context.Context, fileBytes []byte, fileName, message, channel string) (Response, error) {
+	return s.UploadFiles(ctx, []File{
+		{
+			Name:    fileName,
+			Title:   message,
+			AltText: message,
+			Data:    fileBytes,
+		},
+	}, channel, "", message)
 }
 
 func ReturnSlackMessage(inputString Input) Message {
-	// Implement your logic to transform the inputString into a SlackMessage.
-	answer := inputString.Content
-	text := Text{
-		Type: "mrkdwn",
-		Text: answer,
-	}
-
-	block := Block{
-		Type: "section",
-		Text: &text,
+	blocks := MarkdownBlocks(inputString.Content)
+	if len(blocks) > MaxMessageBlocks {
+		blocks = blocks[:MaxMessageBlocks]
 	}
 
 	return Message{
 		Color:  "#f2c744",
-		Blocks: []Block{block},
+		Blocks: blocks,
 	}
 }
 
@@ -0,0 +1,309 @@
+// Package slacktest stellt einen lokalen Fake der Slack Web API bereit. Er beantwortet die
+// Methoden, die der Bot verwendet, und zeichnet jeden Aufruf für Assertions auf.
+//
+//	server := slacktest.NewServer()
+//	defer server.Close()
+//	os.Setenv("SLACK_API_URL", server.APIURL())
+package slacktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Call ist ein aufgezeichneter API-Aufruf.
+type Call struct {
+	Method string
+	Header http.Header
+	Body   []byte
+	// Form enthält die Felder bei form-encoded oder multipart Anfragen.
+	Form url.Values
+	// JSON enthält den dekodierten Body bei application/json.
+	JSON map[string]interface{}
+}
+
+// Member ist ein Eintrag für users.list.
+type Member struct {
+	ID      string `json:"id"`
+	TeamID  string `json:"team_id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+	IsBot   bool   `json:"is_bot"`
+	TZ      string `json:"tz"`
+	Locale  string `json:"locale"`
+	Profile struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type Server struct {
+	*httptest.Server
+
+	// Users wird von users.list ausgeliefert.
+	Users []Member
+	// TeamUsers liefert users.list statt Users, wenn das Token zum Schlüssel passt, z. B.
+	// "xoxb-T123" aus einer Installation über oauth.v2.access.
+	TeamUsers map[string][]Member
+	// BotUserID, BotID und TeamID liefert auth.test.
+	BotUserID string
+	BotID     string
+	TeamID    string
+
+	mu     sync.Mutex
+	calls  []Call
+	seq    int
+	files  map[string]bool
+	socket socketState
+}
+
+func NewServer() *Server {
+	s := &Server{
+		BotUserID: "UBOT",
+		BotID:     "BBOT",
+		TeamID:    "T00000000",
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// APIURL ist die Basis-URL für Slack.BaseURL bzw. SLACK_API_URL.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+// ResponseURL liefert eine neue response_url, wie sie Slack bei Slash-Befehlen mitschickt.
+// Antworten darauf werden als Methode "response_url" aufgezeichnet.
+func (s *Server) ResponseURL() string {
+	return fmt.Sprintf("%s/response/%d", s.URL, s.nextID())
+}
+
+// Calls liefert alle Aufrufe einer Methode, z. B. "chat.postMessage".
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// AllCalls liefert alle Aufrufe in ihrer Reihenfolge.
+func (s *Server) AllCalls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+func (s *Server) nextID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return s.seq
+}
+
+func (s *Server) record(method string, r *http.Request) (Call, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return Call{}, err
+	}
+	call := Call{Method: method, Header: r.Header.Clone(), Body: body, Form: url.Values{}}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json":
+		if err := json.Unmarshal(body, &call.JSON); err != nil {
+			return call, err
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		call.Form, _ = url.ParseQuery(string(body))
+	case strings.HasPrefix(mediaType, "multipart/"):
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		r.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+		if err := r.ParseMultipartForm(32 << 20); err == nil {
+			call.Form = url.Values(r.MultipartForm.Value)
+		}
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	return call, nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	// Upload-Ziel aus files.getUploadURLExternal
+	if strings.HasPrefix(r.URL.Path, "/upload/") {
+		if _, err := s.record("upload", r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("OK - " + strings.TrimPrefix(r.URL.Path, "/upload/")))
+		return
+	}
+
+	if r.URL.Path == socketPath {
+		websocket.Handler(s.serveSocket).ServeHTTP(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/response/") {
+		if _, err := s.record("response_url", r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	call, err := s.record(method, r)
+	if err != nil {
+		writeJSON(w, map[string]interface{}{"ok": false, "error": "invalid_json"})
+		return
+	}
+
+	switch method {
+	case "apps.connections.open":
+		writeJSON(w, map[string]interface{}{"ok": true, "url": s.socketURL()})
+	case "auth.test":
+		writeJSON(w, map[string]interface{}{
+			"ok":      true,
+			"user_id": s.BotUserID,
+			"bot_id":  s.BotID,
+			"team_id": s.TeamID,
+		})
+	case "chat.postMessage":
+		ts := fmt.Sprintf("%d.%06d", time.Now().Unix(), s.nextID())
+		writeJSON(w, map[string]interface{}{
+			"ok":      true,
+			"channel": call.Value("channel"),
+			"ts":      ts,
+			"message": call.JSON,
+		})
+	case "chat.scheduleMessage":
+		writeJSON(w, map[string]interface{}{
+			"ok":                   true,
+			"channel":              call.Value("channel"),
+			"scheduled_message_id": fmt.Sprintf("Q%08d", s.nextID()),
+			"post_at":              call.JSON["post_at"],
+		})
+	case "chat.deleteScheduledMessage":
+		writeJSON(w, map[string]interface{}{"ok": true})
+	case "chat.update":
+		writeJSON(w, map[string]interface{}{
+			"ok":      true,
+			"channel": call.Value("channel"),
+			"ts":      call.Value("ts"),
+			"message": call.JSON,
+		})
+	case "files.upload":
+		writeJSON(w, map[string]interface{}{
+			"ok":   true,
+			"file": map[string]interface{}{"id": fmt.Sprintf("F%08d", s.nextID())},
+		})
+	case "files.getUploadURLExternal":
+		id := fmt.Sprintf("F%08d", s.nextID())
+		s.mu.Lock()
+		if s.files == nil {
+			s.files = make(map[string]bool)
+		}
+		s.files[id] = true
+		s.mu.Unlock()
+		writeJSON(w, map[string]interface{}{
+			"ok":         true,
+			"upload_url": s.URL + "/upload/" + id,
+			"file_id":    id,
+		})
+	case "files.completeUploadExternal":
+		// Wie Slack nur id und title, den Permalink liefert erst files.info
+		var files []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		}
+		json.Unmarshal([]byte(call.Value("files")), &files)
+		writeJSON(w, map[string]interface{}{"ok": true, "files": files})
+	case "files.info":
+		id := call.Value("file")
+		if !s.uploaded(id) {
+			writeJSON(w, map[string]interface{}{"ok": false, "error": "file_not_found"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"ok":   true,
+			"file": map[string]interface{}{"id": id, "permalink": s.URL + "/files/" + id},
+		})
+	case "views.publish":
+		writeJSON(w, map[string]interface{}{
+			"ok":   true,
+			"view": map[string]interface{}{"id": fmt.Sprintf("V%08d", s.nextID())},
+		})
+	case "oauth.v2.access":
+		writeJSON(w, map[string]interface{}{
+			"ok":           true,
+			"access_token": "xoxb-" + s.TeamID,
+			"token_type":   "bot",
+			"scope":        "chat:write",
+			"bot_user_id":  s.BotUserID,
+			"app_id":       "A00000000",
+			"team":         map[string]string{"id": s.TeamID, "name": "Test"},
+			"authed_user":  map[string]string{"id": "U00000000"},
+		})
+	case "users.list":
+		members := s.Users
+		if team, ok := s.TeamUsers[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]; ok {
+			members = team
+		}
+		writeJSON(w, map[string]interface{}{
+			"ok":                true,
+			"members":           members,
+			"response_metadata": map[string]string{"next_cursor": ""},
+		})
+	default:
+		writeJSON(w, map[string]interface{}{"ok": false, "error": "unknown_method"})
+	}
+}
+
+// uploaded meldet, ob files.getUploadURLExternal die ID vergeben hat.
+func (s *Server) uploaded(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[id]
+}
+
+// Value liest ein Feld unabhängig davon, ob es als JSON oder Formular geschickt wurde.
+func (c Call) Value(key string) string {
+	if v, ok := c.JSON[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return c.Form.Get(key)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
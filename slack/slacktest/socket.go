@@ -0,0 +1,145 @@
+package slacktest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// socketPath ist die WebSocket-URL, die apps.connections.open ausliefert.
+const socketPath = "/socket"
+
+// socketState ist der Socket-Mode-Teil des Fake-Servers: die aktuelle Verbindung und die
+// empfangenen Acks nach envelope_id.
+type socketState struct {
+	mu          sync.Mutex
+	conn        *websocket.Conn
+	connections int
+	acks        map[string]json.RawMessage
+}
+
+// socketURL ist die Antwort von apps.connections.open.
+func (s *Server) socketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + socketPath
+}
+
+// serveSocket begrüßt den Client mit hello und sammelt danach seine Acks.
+func (s *Server) serveSocket(conn *websocket.Conn) {
+	if err := websocket.JSON.Send(conn, map[string]interface{}{"type": "hello", "num_connections": 1}); err != nil {
+		return
+	}
+
+	s.socket.mu.Lock()
+	s.socket.conn = conn
+	s.socket.connections++
+	if s.socket.acks == nil {
+		s.socket.acks = make(map[string]json.RawMessage)
+	}
+	s.socket.mu.Unlock()
+
+	for {
+		var ack struct {
+			EnvelopeID string          `json:"envelope_id"`
+			Payload    json.RawMessage `json:"payload"`
+		}
+		if err := websocket.JSON.Receive(conn, &ack); err != nil {
+			break
+		}
+		s.socket.mu.Lock()
+		s.socket.acks[ack.EnvelopeID] = ack.Payload
+		s.socket.mu.Unlock()
+	}
+
+	s.socket.mu.Lock()
+	if s.socket.conn == conn {
+		s.socket.conn = nil
+	}
+	s.socket.mu.Unlock()
+}
+
+// Connections zählt die Socket-Mode-Verbindungen seit dem Start, auch die neu aufgebauten.
+func (s *Server) Connections() int {
+	s.socket.mu.Lock()
+	defer s.socket.mu.Unlock()
+	return s.socket.connections
+}
+
+// WaitConnected wartet, bis mindestens n Socket-Mode-Verbindungen aufgebaut wurden und eine
+// davon offen ist.
+func (s *Server) WaitConnected(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		s.socket.mu.Lock()
+		ok := s.socket.conn != nil && s.socket.connections >= n
+		s.socket.mu.Unlock()
+		if ok {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// SendEnvelope schickt ein Envelope über die offene Verbindung und liefert dessen envelope_id.
+func (s *Server) SendEnvelope(envelopeType string, payload interface{}, acceptsResponse bool) (string, error) {
+	s.socket.mu.Lock()
+	conn := s.socket.conn
+	s.socket.mu.Unlock()
+	if conn == nil {
+		return "", errors.New("slacktest: keine Socket-Mode-Verbindung")
+	}
+
+	id := fmt.Sprintf("E%08d", s.nextID())
+	return id, websocket.JSON.Send(conn, map[string]interface{}{
+		"envelope_id":              id,
+		"type":                     envelopeType,
+		"payload":                  payload,
+		"accepts_response_payload": acceptsResponse,
+	})
+}
+
+// WaitAck wartet auf das Ack zum Envelope und liefert dessen Payload.
+func (s *Server) WaitAck(envelopeID string, timeout time.Duration) (json.RawMessage, bool) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		s.socket.mu.Lock()
+		payload, ok := s.socket.acks[envelopeID]
+		s.socket.mu.Unlock()
+		if ok {
+			return payload, true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil, false
+}
+
+// Disconnect kündigt die Verbindung wie Slack mit einem disconnect-Envelope und schließt sie.
+func (s *Server) Disconnect(reason string) error {
+	s.socket.mu.Lock()
+	conn := s.socket.conn
+	s.socket.conn = nil
+	s.socket.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+
+	err := websocket.JSON.Send(conn, map[string]interface{}{"type": "disconnect", "reason": reason})
+	conn.Close()
+	return err
+}
+
+// DropConnection bricht die Verbindung ohne disconnect ab, wie bei einem Netzwerkfehler.
+func (s *Server) DropConnection() {
+	s.socket.mu.Lock()
+	conn := s.socket.conn
+	s.socket.conn = nil
+	s.socket.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
@@ -0,0 +1,185 @@
+// Package socketmode verbindet die App über Socket Mode mit Slack. Statt HTTP-Anfragen an eine
+// öffentliche URL schickt Slack Events, Slash-Befehle und Interaktionen über eine WebSocket-
+// Verbindung, die der Bot selbst aufbaut. Jede Nachricht (Envelope) muss mit ihrer envelope_id
+// bestätigt werden.
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-slack-ics/slack"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Typen der Envelopes, die Slack schickt.
+const (
+	TypeHello        = "hello"
+	TypeDisconnect   = "disconnect"
+	TypeEventsAPI    = "events_api"
+	TypeSlashCommand = "slash_commands"
+	TypeInteractive  = "interactive"
+)
+
+// Envelope ist eine Nachricht von Slack. Payload entspricht dem Body der HTTP-Variante: dem
+// Event-Callback, den Feldern des Slash-Befehls bzw. dem Interaktions-Payload.
+type Envelope struct {
+	EnvelopeID             string          `json:"envelope_id,omitempty"`
+	Type                   string          `json:"type"`
+	Payload                json.RawMessage `json:"payload,omitempty"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload,omitempty"`
+	RetryAttempt           int             `json:"retry_attempt,omitempty"`
+	RetryReason            string          `json:"retry_reason,omitempty"`
+	// Reason begründet ein disconnect, z. B. "refresh_requested".
+	Reason string `json:"reason,omitempty"`
+}
+
+// Ack bestätigt ein Envelope. Payload ist die Antwort, z. B. auf einen Slash-Befehl.
+type Ack struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// Handler verarbeitet ein Envelope. Die Antwort wird mit dem Ack zurückgeschickt, wenn Slack
+// sie annimmt. Slack wartet höchstens drei Sekunden auf das Ack.
+type Handler func(ctx context.Context, envelope Envelope) (json.RawMessage, error)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+	dialTimeout       = 10 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+// Client hält die Verbindung und baut sie nach Abbrüchen oder einem disconnect neu auf.
+type Client struct {
+	// AppToken ist das App-Level-Token mit dem Scope connections:write.
+	AppToken string
+	Handler  Handler
+	// Slack öffnet die Verbindung, z. B. gegen den Fake-Server aus slack/slacktest.
+	Slack *slack.Slack
+	// MinBackoff und MaxBackoff begrenzen die Wartezeit vor einem erneuten Verbindungsversuch.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Run verbindet sich und verarbeitet Envelopes, bis ctx beendet ist.
+func (c *Client) Run(ctx context.Context) error {
+	minBackoff, maxBackoff := c.MinBackoff, c.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	backoff := minBackoff
+	for {
+		connected, err := c.connectAndServe(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			backoff = minBackoff
+		}
+		if err == nil {
+			// disconnect von Slack: sofort eine neue Verbindung öffnen
+			continue
+		}
+
+		log.Printf("Socket Mode getrennt, neuer Versuch in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connectAndServe öffnet eine Verbindung und liest bis zum Abbruch. connected meldet, ob Slack
+// die Verbindung mit hello angenommen hat; ein disconnect von Slack ergibt err == nil.
+func (c *Client) connectAndServe(ctx context.Context) (connected bool, err error) {
+	api := c.Slack
+	if api == nil {
+		api = &slack.Instance
+	}
+	wsURL, err := api.OpenConnection(ctx, c.AppToken)
+	if err != nil {
+		return false, err
+	}
+
+	config, err := websocket.NewConfig(wsURL, "https://slack.com/")
+	if err != nil {
+		return false, err
+	}
+	config.Dialer = &net.Dialer{Timeout: dialTimeout}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return false, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	for {
+		var envelope Envelope
+		if err := websocket.JSON.Receive(conn, &envelope); err != nil {
+			return connected, err
+		}
+
+		switch envelope.Type {
+		case TypeHello:
+			connected = true
+			log.Printf("Socket Mode verbunden")
+		case TypeDisconnect:
+			log.Printf("Socket Mode: Slack trennt die Verbindung (%s)", envelope.Reason)
+			return connected, nil
+		default:
+			go c.handle(ctx, conn, &writeMu, envelope)
+		}
+	}
+}
+
+// handle ruft den Handler auf und bestätigt das Envelope danach mit dessen Antwort.
+func (c *Client) handle(ctx context.Context, conn *websocket.Conn, writeMu *sync.Mutex, envelope Envelope) {
+	var payload json.RawMessage
+	if c.Handler != nil {
+		var err error
+		payload, err = c.Handler(ctx, envelope)
+		if err != nil {
+			log.Printf("Fehler bei Socket-Mode-Nachricht %s (%s): %v", envelope.EnvelopeID, envelope.Type, err)
+		}
+	}
+	if envelope.EnvelopeID == "" {
+		return
+	}
+
+	ack := Ack{EnvelopeID: envelope.EnvelopeID}
+	if envelope.AcceptsResponsePayload && json.Valid(payload) {
+		ack.Payload = payload
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := websocket.JSON.Send(conn, ack); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("Ack für %s fehlgeschlagen: %v", envelope.EnvelopeID, err)
+	}
+}
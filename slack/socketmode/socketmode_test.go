@@ -0,0 +1,163 @@
+package socketmode_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-slack-ics/slack"
+	"go-slack-ics/slack/slacktest"
+	"go-slack-ics/slack/socketmode"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// apps.connections.open erlaubt nur einen Aufruf pro Minute, die Tests verbinden sich öfter
+	slack.DefaultClient.Limits = map[string]int{"apps.connections.open": 6000}
+	os.Exit(m.Run())
+}
+
+// start verbindet einen Client mit einem neuen Fake-Server und beendet ihn am Ende des Tests.
+func start(t *testing.T, handler socketmode.Handler) (*slacktest.Server, <-chan error) {
+	t.Helper()
+	server := slacktest.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	client := socketmode.Client{
+		AppToken:   "xapp-test",
+		Handler:    handler,
+		Slack:      &slack.Slack{BaseURL: server.APIURL()},
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- client.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		server.Close()
+	})
+
+	if !server.WaitConnected(1, 2*time.Second) {
+		t.Fatal("keine Socket-Mode-Verbindung")
+	}
+	return server, done
+}
+
+func echo(ctx context.Context, envelope socketmode.Envelope) (json.RawMessage, error) {
+	return json.RawMessage(`{"text":"` + envelope.Type + `"}`), nil
+}
+
+func TestAck(t *testing.T) {
+	tests := []struct {
+		name            string
+		handler         socketmode.Handler
+		acceptsResponse bool
+		payload         string
+	}{
+		{"mit Antwort", echo, true, `{"text":"slash_commands"}`},
+		{"Antwort nicht angenommen", echo, false, ""},
+		{"ohne Handler", nil, true, ""},
+		{"Fehler im Handler", func(context.Context, socketmode.Envelope) (json.RawMessage, error) {
+			return nil, errors.New("kaputt")
+		}, true, ""},
+		{"ungültige Antwort", func(context.Context, socketmode.Envelope) (json.RawMessage, error) {
+			return json.RawMessage("{kaputt"), nil
+		}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := start(t, tt.handler)
+			id, err := server.SendEnvelope(socketmode.TypeSlashCommand, map[string]string{"command": "/abfuhr"}, tt.acceptsResponse)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload, ok := server.WaitAck(id, 2*time.Second)
+			if !ok {
+				t.Fatalf("kein Ack für %s", id)
+			}
+			if string(payload) != tt.payload {
+				t.Fatalf("Ack-Payload %s, erwartet %q", payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestHandlerReceivesEnvelope(t *testing.T) {
+	received := make(chan socketmode.Envelope, 1)
+	server, _ := start(t, func(ctx context.Context, envelope socketmode.Envelope) (json.RawMessage, error) {
+		received <- envelope
+		return nil, nil
+	})
+
+	id, err := server.SendEnvelope(socketmode.TypeEventsAPI, map[string]string{"type": "event_callback"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case envelope := <-received:
+		if envelope.EnvelopeID != id || envelope.Type != socketmode.TypeEventsAPI || string(envelope.Payload) != `{"type":"event_callback"}` {
+			t.Fatalf("Envelope %+v", envelope)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler nicht aufgerufen")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		drop func(server *slacktest.Server)
+	}{
+		{"disconnect", func(server *slacktest.Server) { server.Disconnect("refresh_requested") }},
+		{"abgebrochene Verbindung", func(server *slacktest.Server) { server.DropConnection() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := start(t, echo)
+
+			for n := 2; n <= 3; n++ {
+				tt.drop(server)
+				if !server.WaitConnected(n, 2*time.Second) {
+					t.Fatalf("Verbindung %d nicht aufgebaut, %d bisher", n, server.Connections())
+				}
+			}
+			if n := len(server.Calls("apps.connections.open")); n != 3 {
+				t.Fatalf("%d Aufrufe von apps.connections.open, erwartet 3", n)
+			}
+
+			// Die neue Verbindung verarbeitet wieder Envelopes
+			id, err := server.SendEnvelope(socketmode.TypeInteractive, map[string]string{"type": "block_actions"}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payload, ok := server.WaitAck(id, 2*time.Second); !ok || string(payload) != `{"text":"interactive"}` {
+				t.Fatalf("Ack nach dem Neuaufbau: %s, %v", payload, ok)
+			}
+		})
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	server := slacktest.NewServer()
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	client := socketmode.Client{AppToken: "xapp-test", Slack: &slack.Slack{BaseURL: server.APIURL()}}
+
+	done := make(chan error, 1)
+	go func() { done <- client.Run(ctx) }()
+	if !server.WaitConnected(1, 2*time.Second) {
+		t.Fatal("keine Socket-Mode-Verbindung")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run endet mit %v, erwartet context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run endet nicht nach dem Abbruch")
+	}
+}
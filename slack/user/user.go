@@ -1,15 +1,295 @@
+// Package user hält das Benutzerverzeichnis der Workspaces. Es wird regelmäßig aus users.list
+// jedes Workspaces synchronisiert, in Redis zwischengespeichert und löst Namen aus der
+// Konfiguration in IDs auf.
 package user
 
-import "os"
+import (
+	"context"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	"go-slack-ics/system"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
 
 type User struct {
-	Slack string
+	ID          string `json:"id"`
+	TeamID      string `json:"team_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	RealName    string `json:"real_name"`
+	TZ          string `json:"tz"`
+	Locale      string `json:"locale"`
+	IsBot       bool   `json:"is_bot"`
+	Deleted     bool   `json:"deleted"`
 }
 
-var Users map[string]string
+// Label ist der Name, unter dem der User im Workspace angezeigt wird.
+func (u User) Label() string {
+	switch {
+	case u.DisplayName != "":
+		return u.DisplayName
+	case u.RealName != "":
+		return u.RealName
+	case u.Name != "":
+		return u.Name
+	}
+	return u.ID
+}
+
+// directoryKey ist der Redis-Key des Verzeichnisses eines Workspaces. "" steht für den Workspace
+// von SLACK_TOKEN und behält den bisherigen Key.
+func directoryKey(teamID string) string {
+	if teamID == "" {
+		return "slack:users"
+	}
+	return "slack:users:" + teamID
+}
+
+// Directory ist das Benutzerverzeichnis, nach team_id und ID indiziert. Das Team "" ist der
+// Workspace von SLACK_TOKEN.
+type Directory struct {
+	mu       sync.RWMutex
+	teams    map[string]map[string]User
+	syncedAt time.Time
+}
+
+func NewDirectory() *Directory {
+	return &Directory{teams: make(map[string]map[string]User)}
+}
+
+var DefaultDirectory = NewDirectory()
+
+// Load übernimmt den letzten Stand aus Redis für den Workspace von SLACK_TOKEN und alle
+// Installationen, damit Namen schon vor dem ersten Sync aufgelöst werden können.
+func (d *Directory) Load() error {
+	teams, err := installedTeams()
+	for _, teamID := range append([]string{""}, teams...) {
+		var users map[string]User
+		if loadErr := system.RedisInstance().Get(directoryKey(teamID), "", &users); loadErr != nil {
+			if err == nil {
+				err = loadErr
+			}
+			continue
+		}
+		d.mu.Lock()
+		d.teams[teamID] = users
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// installedTeams liefert die team_ids der Installationen über /slack/install.
+func installedTeams() ([]string, error) {
+	if slack.Installations == nil {
+		return nil, nil
+	}
+	return slack.Installations.Teams()
+}
+
+// Sync lädt alle Seiten von users.list und ersetzt das Verzeichnis des Workspaces. ctx bestimmt
+// mit slack.WithTeam den Workspace, ohne Team ist es der von SLACK_TOKEN.
+func (d *Directory) Sync(ctx context.Context) error {
+	teamID := slack.TeamFromContext(ctx)
+	users := make(map[string]User)
+	cursor := ""
+	for {
+		members, next, err := slack.Instance.ListUsers(ctx, cursor)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			users[member.ID] = User{
+				ID:          member.ID,
+				TeamID:      member.TeamID,
+				Name:        member.Name,
+				DisplayName: member.Profile.DisplayName,
+				RealName:    member.Profile.RealName,
+				TZ:          member.TZ,
+				Locale:      member.Locale,
+				IsBot:       member.IsBot,
+				Deleted:     member.Deleted,
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	d.mu.Lock()
+	d.teams[teamID] = users
+	d.syncedAt = time.Now()
+	d.mu.Unlock()
+
+	return system.RedisInstance().Set(directoryKey(teamID), users, "")
+}
+
+// SyncAll synchronisiert den Workspace von SLACK_TOKEN und jede Installation mit deren Token.
+// Fehler einzelner Workspaces werden protokolliert und halten die übrigen nicht auf. Teams ohne
+// Installation fallen aus dem Verzeichnis.
+func (d *Directory) SyncAll(ctx context.Context) error {
+	teams, err := installedTeams()
+	if err != nil {
+		log.Printf("Fehler beim Laden der Installationen: %v", err)
+		return err
+	}
+	var firstErr error
+	installed := map[string]bool{"": true}
+	for _, teamID := range append([]string{""}, teams...) {
+		installed[teamID] = true
+		if err := d.Sync(slack.WithTeam(ctx, teamID)); err != nil {
+			log.Printf("Fehler beim Synchronisieren der Slack-User von %q: %v", teamID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for teamID := range d.teams {
+		if !installed[teamID] {
+			delete(d.teams, teamID)
+			system.RedisInstance().Del(directoryKey(teamID))
+		}
+	}
+	return firstErr
+}
+
+// Run synchronisiert sofort und danach im angegebenen Abstand alle Workspaces, bis ctx beendet ist.
+func (d *Directory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.SyncAll(ctx); err == nil {
+			log.Printf("%d Slack-User synchronisiert", d.Len())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Len zählt die User aller Workspaces.
+func (d *Directory) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	n := 0
+	for _, users := range d.teams {
+		n += len(users)
+	}
+	return n
+}
+
+func (d *Directory) SyncedAt() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.syncedAt
+}
+
+// Get sucht die ID in allen Workspaces, User-IDs sind über Workspaces hinweg eindeutig.
+func (d *Directory) Get(id string) (User, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, users := range d.teams {
+		if u, ok := users[id]; ok {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// Find sucht im Workspace teamID einen aktiven User über ID, Anzeigenamen, vollen Namen oder
+// Benutzernamen, ohne auf Groß- und Kleinschreibung zu achten. Ein führendes @ wird ignoriert.
+func (d *Directory) Find(teamID, name string) (User, bool) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if name == "" {
+		return User{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	users := d.teams[teamID]
+	if u, ok := users[name]; ok {
+		return u, true
+	}
+	for _, u := range users {
+		if u.Deleted || u.IsBot {
+			continue
+		}
+		if strings.EqualFold(u.DisplayName, name) || strings.EqualFold(u.RealName, name) || strings.EqualFold(u.Name, name) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// DisplayName liefert den Anzeigenamen zur ID oder die ID, wenn der User unbekannt ist.
+func DisplayName(id string) string {
+	if u, ok := DefaultDirectory.Get(id); ok {
+		return u.Label()
+	}
+	return id
+}
+
+// Resolve liefert die Slack-ID zu einem Namen aus der Konfiguration, z. B. "Frank". Ist
+// SLACK_<NAME> gesetzt, gilt dessen Wert (ID oder Name), sonst wird der Name selbst im
+// Verzeichnis des Workspaces von SLACK_TOKEN gesucht. Unbekannte Namen ergeben "".
+func Resolve(name string) string {
+	if configured := os.Getenv("SLACK_" + strings.ToUpper(name)); configured != "" {
+		if u, ok := DefaultDirectory.Find("", configured); ok {
+			return u.ID
+		}
+		// Vor dem ersten Sync ist eine konfigurierte ID trotzdem gültig
+		if looksLikeID(configured) {
+			return configured
+		}
+		return ""
+	}
+	if u, ok := DefaultDirectory.Find("", name); ok {
+		return u.ID
+	}
+	return ""
+}
+
+// looksLikeID erkennt User-IDs wie U0123ABCD oder W0123ABCD.
+func looksLikeID(s string) bool {
+	if len(s) < 9 || (s[0] != 'U' && s[0] != 'W') {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Locale liefert die Sprache für Texte an den User: seine Einstellung, sonst die Sprache seines
+// Slack-Profils, sonst i18n.Default.
+func Locale(id string) string {
+	if locale := i18n.Preference(id); locale != "" {
+		return locale
+	}
+	if u, ok := DefaultDirectory.Get(id); ok {
+		if locale := i18n.Normalize(u.Locale); locale != "" {
+			return locale
+		}
+	}
+	return i18n.Default()
+}
 
-func InitUsers() {
-	Users = make(map[string]string)
-	Users["Frank"] = os.Getenv("SLACK_FRANK")
-	Users["Wolf"] = os.Getenv("SLACK_WOLF")
+// Mention liefert die Erwähnung <@ID> für einen Namen oder den Namen selbst, wenn er unbekannt ist.
+func Mention(name string) string {
+	if id := Resolve(name); id != "" {
+		return "<@" + id + ">"
+	}
+	return name
 }
@@ -0,0 +1,136 @@
+package user
+
+import (
+	"context"
+	"go-slack-ics/slack"
+	"go-slack-ics/slack/slacktest"
+	"go-slack-ics/system"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func member(id, teamID, displayName string) slacktest.Member {
+	m := slacktest.Member{ID: id, TeamID: teamID, Name: displayName}
+	m.Profile.DisplayName = displayName
+	return m
+}
+
+var (
+	fake  *slacktest.Server
+	redis *miniredis.Miniredis
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	if redis, err = miniredis.Run(); err != nil {
+		log.Fatal(err)
+	}
+	fake = slacktest.NewServer()
+
+	os.Setenv("REDIS_ADDR", redis.Addr())
+	os.Setenv("SLACK_API_URL", fake.APIURL())
+	os.Setenv("SLACK_TOKEN", "xoxb-test")
+	// users.list ist bei Slack auf 20 Aufrufe pro Minute begrenzt
+	slack.DefaultClient.Limits = map[string]int{"users.list": 6000}
+
+	code := m.Run()
+	fake.Close()
+	redis.Close()
+	os.Exit(code)
+}
+
+// setup legt zwei Workspaces an: den von SLACK_TOKEN und die Installation T2.
+func setup(t *testing.T) {
+	redis.FlushAll()
+	fake.Users = []slacktest.Member{member("U1", "T1", "Frank")}
+	fake.TeamUsers = map[string][]slacktest.Member{
+		"xoxb-T2": {member("U2", "T2", "Frank"), member("U3", "T2", "Wolf")},
+	}
+	if err := slack.Installations.Save(slack.Installation{TeamID: "T2", BotUserID: "B2", BotToken: "xoxb-T2"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncAllPerTeam(t *testing.T) {
+	setup(t)
+	d := NewDirectory()
+	if err := d.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Len() != 3 {
+		t.Fatalf("%d User, erwartet 3", d.Len())
+	}
+	for teamID, want := range map[string]string{"": "U1", "T2": "U2"} {
+		if u, ok := d.Find(teamID, "frank"); !ok || u.ID != want {
+			t.Errorf("Find(%q, frank) = %s, erwartet %s", teamID, u.ID, want)
+		}
+	}
+	if u, ok := d.Find("", "Wolf"); ok {
+		t.Errorf("Wolf aus T2 im Workspace von SLACK_TOKEN gefunden: %s", u.ID)
+	}
+	if u, ok := d.Get("U3"); !ok || u.TeamID != "T2" {
+		t.Errorf("Get(U3) = %v", u)
+	}
+
+	// Jeder Workspace hat seinen eigenen Key in Redis
+	for teamID, n := range map[string]int{"": 1, "T2": 2} {
+		var users map[string]User
+		if err := system.RedisInstance().Get(directoryKey(teamID), "", &users); err != nil || len(users) != n {
+			t.Errorf("%s: %d User (%v), erwartet %d", directoryKey(teamID), len(users), err, n)
+		}
+	}
+
+	loaded := NewDirectory()
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := loaded.Find("T2", "wolf"); !ok || u.ID != "U3" {
+		t.Errorf("nach Load: Find(T2, wolf) = %s", u.ID)
+	}
+}
+
+func TestSyncAllDropsUninstalledTeams(t *testing.T) {
+	setup(t)
+	d := NewDirectory()
+	d.SyncAll(context.Background())
+
+	if err := slack.Installations.Delete("T2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get("U2"); ok {
+		t.Error("User aus T2 nach der Deinstallation noch im Verzeichnis")
+	}
+	if ok, _ := system.RedisInstance().Exists(directoryKey("T2")); ok {
+		t.Errorf("%s nach der Deinstallation noch in Redis", directoryKey("T2"))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	setup(t)
+	saved := DefaultDirectory
+	DefaultDirectory = NewDirectory()
+	t.Cleanup(func() { DefaultDirectory = saved })
+	DefaultDirectory.SyncAll(context.Background())
+
+	os.Unsetenv("SLACK_FRANK")
+	t.Setenv("SLACK_WOLF", "UWOLF0001")
+	tests := map[string]string{
+		// Frank gibt es in beiden Workspaces, Resolve nimmt den von SLACK_TOKEN
+		"Frank": "U1",
+		// Konfigurierte IDs gelten auch ohne Eintrag im Verzeichnis
+		"Wolf":      "UWOLF0001",
+		"Unbekannt": "",
+	}
+	for name, want := range tests {
+		if got := Resolve(name); got != want {
+			t.Errorf("Resolve(%s) = %q, erwartet %q", name, got, want)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -45,6 +47,23 @@ func NewRedis() *Redis {
 	}
 }
 
+// ErrNil meldet, dass ein Key nicht existiert.
+var ErrNil = redis.Nil
+
+var (
+	redisInstance *Redis
+	redisOnce     sync.Once
+)
+
+// RedisInstance liefert eine gemeinsam genutzte Verbindung. Sie wird erst beim ersten
+// Aufruf aufgebaut, damit die Umgebungsvariablen aus der .env bereits geladen sind.
+func RedisInstance() *Redis {
+	redisOnce.Do(func() {
+		redisInstance = NewRedis()
+	})
+	return redisInstance
+}
+
 // Set serialisiert den Wert (value) als JSON und speichert ihn unter dem angegebenen Key.
 func (r *Redis) Set(key string, value interface{}, alias string) error {
 	data, err := json.Marshal(value)
@@ -52,10 +71,17 @@ func (r *Redis) Set(key string, value interface{}, alias string) error {
 		return err
 	}
 
-	r.client.Set(r.ctx, alias, key, 0)
+	if alias != "" {
+		r.client.Set(r.ctx, alias, key, 0)
+	}
 	return r.client.Set(r.ctx, key, data, 0).Err() // 0 = kein Ablaufdatum
 }
 
+// SetNX speichert den Wert nur, wenn der Key noch nicht existiert, und liefert true, wenn er gesetzt wurde.
+func (r *Redis) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(r.ctx, key, value, ttl).Result()
+}
+
 // Get lädt den Wert für den gegebenen Key, deserialisiert ihn aus JSON
 // und schreibt das Ergebnis in target (das als Pointer übergeben werden muss).
 func (r *Redis) Get(key string, aliasKey string, target interface{}) error {
@@ -89,8 +115,46 @@ func (r *Redis) Del(key string) error {
 	return r.client.Del(r.ctx, key).Err()
 }
 
+// Take löscht den Key und meldet, ob er vorhanden war. Bei gleichzeitigen Aufrufen bekommt nur
+// einer true, damit lassen sich Einmal-Tokens verbrauchen.
+func (r *Redis) Take(key string) (bool, error) {
+	n, err := r.client.Del(r.ctx, key).Result()
+	return n > 0, err
+}
+
 // LRem entfernt Elemente aus der Liste, die dem übergebenen Wert entsprechen.
 // count steuert dabei, wie viele Vorkommen entfernt werden sollen.
 func (r *Redis) LRem(key string, count int64, value interface{}) error {
 	return r.client.LRem(r.ctx, key, count, value).Err()
 }
+
+// LTrim kürzt die Liste auf die Elemente zwischen start und stop.
+func (r *Redis) LTrim(key string, start, stop int64) error {
+	return r.client.LTrim(r.ctx, key, start, stop).Err()
+}
+
+// Incr erhöht den Zähler unter key um eins und liefert den neuen Wert.
+func (r *Redis) Incr(key string) (int64, error) {
+	return r.client.Incr(r.ctx, key).Result()
+}
+
+// Exists prüft, ob der Key vorhanden ist.
+func (r *Redis) Exists(key string) (bool, error) {
+	n, err := r.client.Exists(r.ctx, key).Result()
+	return n > 0, err
+}
+
+// Eval führt ein Lua-Skript aus. Redis führt es atomar aus, kein anderer Befehl läuft dazwischen.
+func (r *Redis) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.client.Eval(r.ctx, script, keys, args...).Result()
+}
+
+// Scan liefert alle Keys, die auf pattern passen. Anders als KEYS blockiert SCAN Redis nicht.
+func (r *Redis) Scan(pattern string) ([]string, error) {
+	var keys []string
+	iter := r.client.Scan(r.ctx, 0, pattern, 100).Iterator()
+	for iter.Next(r.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
@@ -0,0 +1,160 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"go-slack-ics/calendar"
+	"go-slack-ics/clipdrop"
+	"go-slack-ics/home"
+	"go-slack-ics/i18n"
+	"go-slack-ics/leonardo"
+	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
+	"log"
+	"strings"
+	"time"
+)
+
+// Namen der Slash-Befehle zur Bilderzeugung.
+const (
+	commandTextToImage = "/text-to-image"
+	commandClipdrop    = "/clipdrop"
+)
+
+// newCommandRouter registriert alle Slash-Befehle.
+func newCommandRouter() *slack.CommandRouter {
+	commands := slack.NewCommandRouter()
+	commands.Locale = slackUser.Locale
+
+	calendar.RegisterCommands(commands)
+	commands.AddSubcommand(calendar.CommandName, slack.Subcommand{
+		Name:    "sprache",
+		Aliases: []string{"language"},
+		Usage:   "command.language.usage",
+		Help:    "command.language",
+		Handler: languageCommand,
+	})
+
+	commands.Register(slack.CommandSpec{
+		Name:        commandTextToImage,
+		Description: "command.text_to_image",
+		Usage:       "command.image.usage",
+		Args:        parsePrompt,
+		Handler:     leonardoCommand,
+	})
+	commands.Register(slack.CommandSpec{
+		Name:        commandClipdrop,
+		Description: "command.clipdrop",
+		Usage:       "command.image.usage",
+		Args:        parsePrompt,
+		Handler:     clipdropCommand,
+	})
+	return commands
+}
+
+// languageCommand stellt die Sprache für /abfuhr sprache ein. "auto" oder ein leeres Argument
+// löscht die Einstellung, dann gilt wieder das Slack-Profil.
+func languageCommand(_ context.Context, req *slack.CommandRequest) (slack.ResponseMessage, error) {
+	if req.Args == "" || req.Args == "auto" {
+		if err := i18n.SetPreference(req.UserID, ""); err != nil {
+			return slack.ResponseMessage{}, err
+		}
+		return slack.EphemeralResponse(i18n.T(slackUser.Locale(req.UserID), "command.language_reset")), nil
+	}
+
+	chosen := i18n.Normalize(req.Args)
+	if chosen == "" {
+		return slack.EphemeralResponse(i18n.T(req.Locale, "command.language_unknown", strings.Join(i18n.Supported(), ", "))), nil
+	}
+	if err := i18n.SetPreference(req.UserID, chosen); err != nil {
+		return slack.ResponseMessage{}, err
+	}
+	return slack.EphemeralResponse(i18n.T(chosen, "command.language_set")), nil
+}
+
+// parsePrompt verlangt eine Beschreibung für das Bild.
+func parsePrompt(args string, locale string) (interface{}, error) {
+	if args == "" {
+		return nil, errors.New(i18n.T(locale, "image.prompt_missing"))
+	}
+	return args, nil
+}
+
+// leonardoCommand erzeugt das Bild im Hintergrund und bestätigt den Befehl sofort. Zwischenstand
+// und Ergebnis kommen über die response_url.
+func leonardoCommand(_ context.Context, req *slack.CommandRequest) (slack.ResponseMessage, error) {
+	event, locale := req.Command, req.Locale
+	event.Text = req.Args
+	responder := event.Responder()
+
+	go func() {
+		// Der Request ist nach der Antwort beendet
+		ctx, cancel := context.WithTimeout(slack.WithTeam(context.Background(), event.TeamID), imageTimeout)
+		defer cancel()
+
+		bodyBytes, filename, err := leonardo.NewTextToImage(ctx, event.Text)
+		if err != nil {
+			finishImage(ctx, responder, event, locale, "", err)
+			return
+		}
+
+		if err := responder.Progress(ctx, i18n.T(locale, "image.uploading")); err != nil {
+			log.Printf("Zwischenstand für %s nicht gesendet: %v", event.UserID, err)
+		}
+		uploaded, err := slack.Instance.SendImageToSlack(ctx, bodyBytes, filename, event.Text, event.ChannelID)
+		if err != nil {
+			finishImage(ctx, responder, event, locale, "", err)
+			return
+		}
+		image := home.Image{Prompt: event.Text, Source: "Leonardo", At: time.Now()}
+		if len(uploaded.Files) > 0 {
+			image.Permalink = uploaded.Files[0].Permalink
+		}
+		home.RecordImage(event.UserID, image)
+		finishImage(ctx, responder, event, locale, image.Permalink, nil)
+	}()
+
+	return slack.EphemeralResponse(i18n.T(locale, "image.generating")), nil
+}
+
+func clipdropCommand(_ context.Context, req *slack.CommandRequest) (slack.ResponseMessage, error) {
+	event, locale := req.Command, req.Locale
+	event.Text = req.Args
+	responder := event.Responder()
+	tti := clipdrop.NewTextToImage()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(slack.WithTeam(context.Background(), event.TeamID), imageTimeout)
+		defer cancel()
+
+		result, err := tti.Prompt(ctx, event)
+		if err != nil {
+			finishImage(ctx, responder, event, locale, "", err)
+			return
+		}
+		home.RecordImage(event.UserID, home.Image{Prompt: result.Text, Permalink: result.ImageUrl, Source: "Clipdrop", At: time.Now()})
+		finishImage(ctx, responder, event, locale, result.ImageUrl, nil)
+	}()
+
+	return slack.EphemeralResponse(i18n.T(locale, "image.generating")), nil
+}
+
+// finishImage ersetzt die ephemere Antwort auf den Befehl durch das Ergebnis. Ist die
+// response_url abgelaufen oder aufgebraucht, geht ein Fehler wie bisher in den Kanal.
+func finishImage(ctx context.Context, responder *slack.Responder, event slack.Command, locale string, permalink string, err error) {
+	text := i18n.T(locale, "image.done", event.Text)
+	if permalink != "" {
+		text = i18n.T(locale, "image.done_link", permalink, event.Text)
+	}
+	if err != nil {
+		log.Printf("Fehler bei %s für %s: %v", event.Command, event.UserID, err)
+		text = i18n.T(locale, "error.generic", err.Error())
+	}
+
+	if respondErr := responder.Final(ctx, text); respondErr != nil {
+		log.Printf("Antwort über response_url fehlgeschlagen: %v", respondErr)
+		if err != nil {
+			reportError(ctx, event.ChannelID, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"go-slack-ics/i18n"
+	"go-slack-ics/slack"
+	slackUser "go-slack-ics/slack/user"
+	"go-slack-ics/system"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// stateTTL begrenzt, wie lange ein Installationslink gültig ist.
+const stateTTL = 10 * time.Minute
+
+// stateCookie bindet den state an den Browser, der die Installation begonnen hat.
+const stateCookie = "slack_oauth_state"
+
+func stateKey(state string) string {
+	return "slack:oauth:state:" + state
+}
+
+// allowedTeams liest SLACK_ALLOWED_TEAMS (kommagetrennte Team-IDs). Ohne Eintrag darf jeder
+// Workspace die App installieren.
+func allowedTeams() map[string]bool {
+	teams := make(map[string]bool)
+	for _, team := range strings.Split(os.Getenv("SLACK_ALLOWED_TEAMS"), ",") {
+		if team = strings.TrimSpace(team); team != "" {
+			teams[team] = true
+		}
+	}
+	return teams
+}
+
+// oauthRoutes richtet die Installation per OAuth v2 ein: /slack/install leitet zu Slack weiter,
+// /slack/oauth/callback tauscht den Code und speichert das Bot-Token des Workspaces.
+// Benötigt SLACK_CLIENT_ID, SLACK_CLIENT_SECRET und optional SLACK_REDIRECT_URL, SLACK_SCOPES und
+// SLACK_ALLOWED_TEAMS.
+func oauthRoutes(r *gin.Engine) {
+	r.GET("/slack/install", func(c *gin.Context) {
+		locale := i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+		clientID := os.Getenv("SLACK_CLIENT_ID")
+		if clientID == "" {
+			c.String(500, i18n.T(locale, "web.client_id_missing"))
+			return
+		}
+
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			c.String(500, err.Error())
+			return
+		}
+		state := hex.EncodeToString(buf)
+		if _, err := system.RedisInstance().SetNX(stateKey(state), 1, stateTTL); err != nil {
+			c.String(500, err.Error())
+			return
+		}
+
+		secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(stateCookie, state, int(stateTTL.Seconds()), "/slack/oauth", "", secure, true)
+
+		scopes := os.Getenv("SLACK_SCOPES")
+		if scopes == "" {
+			scopes = slack.DefaultScopes
+		}
+		c.Redirect(302, slack.InstallURL(clientID, scopes, os.Getenv("SLACK_REDIRECT_URL"), state))
+	})
+
+	r.GET("/slack/oauth/callback", func(c *gin.Context) {
+		locale := i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+		if reason := c.Query("error"); reason != "" {
+			c.String(400, i18n.T(locale, "web.install_cancelled", reason))
+			return
+		}
+
+		// Der state schützt vor untergeschobenen Codes: Er muss zum Cookie des Browsers passen,
+		// der die Installation begonnen hat, und gilt nur einmal
+		state := c.Query("state")
+		cookie, _ := c.Cookie(stateCookie)
+		c.SetCookie(stateCookie, "", -1, "/slack/oauth", "", false, true)
+		if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookie)) != 1 {
+			c.String(400, i18n.T(locale, "web.install_link_stale"))
+			return
+		}
+		if valid, err := system.RedisInstance().Take(stateKey(state)); err != nil || !valid {
+			c.String(400, i18n.T(locale, "web.install_link_stale"))
+			return
+		}
+
+		installation, err := slack.Instance.ExchangeCode(c.Request.Context(), os.Getenv("SLACK_CLIENT_ID"),
+			os.Getenv("SLACK_CLIENT_SECRET"), c.Query("code"), os.Getenv("SLACK_REDIRECT_URL"))
+		if err != nil {
+			log.Printf("OAuth-Installation fehlgeschlagen: %v", err)
+			c.String(502, i18n.T(locale, "web.install_failed", err.Error()))
+			return
+		}
+		if teams := allowedTeams(); len(teams) > 0 && !teams[installation.TeamID] {
+			log.Printf("Installation in Workspace %s (%s) abgelehnt, nicht in SLACK_ALLOWED_TEAMS", installation.TeamName, installation.TeamID)
+			c.String(403, i18n.T(locale, "web.team_not_allowed", installation.TeamName))
+			return
+		}
+		if err := slack.Installations.Save(installation); err != nil {
+			c.String(500, err.Error())
+			return
+		}
+
+		log.Printf("App in Workspace %s (%s) installiert", installation.TeamName, installation.TeamID)
+		// Die User des neuen Workspaces nicht erst beim nächsten Sync-Intervall laden
+		go func(teamID string) {
+			if err := slackUser.DefaultDirectory.Sync(slack.WithTeam(context.Background(), teamID)); err != nil {
+				log.Printf("Fehler beim Synchronisieren der Slack-User von %s: %v", teamID, err)
+			}
+		}(installation.TeamID)
+		c.String(200, i18n.T(locale, "web.installed", installation.TeamName))
+	})
+}
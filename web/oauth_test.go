@@ -0,0 +1,146 @@
+package web
+
+import (
+	"go-slack-ics/slack"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// install ruft /slack/install auf und liefert den state und das Cookie für den Callback.
+func install(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	t.Setenv("SLACK_CLIENT_ID", "123.456")
+	w := httptest.NewRecorder()
+	router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slack/install", nil))
+	if w.Code != 302 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == stateCookie {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("Cookie %s ist nicht HttpOnly und SameSite=Lax", cookie.Name)
+			}
+			return state, cookie
+		}
+	}
+	t.Fatal("kein state-Cookie gesetzt")
+	return "", nil
+}
+
+// callback ruft /slack/oauth/callback mit state und optional dem Cookie auf.
+func callback(state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/slack/oauth/callback?code=c0de&state="+url.QueryEscape(state), nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router().ServeHTTP(w, r)
+	return w
+}
+
+func forgetInstallation(t *testing.T) {
+	t.Cleanup(func() { slack.Installations.Delete(fake.TeamID) })
+}
+
+func TestOAuthInstall(t *testing.T) {
+	forgetInstallation(t)
+	state, cookie := install(t)
+	if cookie.Value != state {
+		t.Fatalf("Cookie %q passt nicht zum state %q", cookie.Value, state)
+	}
+
+	if w := callback(state, cookie); w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+	installation, err := slack.Installations.Load(fake.TeamID)
+	if err != nil || installation.BotToken == "" {
+		t.Fatalf("Installation nicht gespeichert: %+v, %v", installation, err)
+	}
+
+	// Der state gilt nur einmal
+	if w := callback(state, cookie); w.Code != 400 {
+		t.Fatalf("zweiter Callback: Status %d, erwartet 400", w.Code)
+	}
+}
+
+func TestOAuthCallbackRejected(t *testing.T) {
+	forgetInstallation(t)
+	state, cookie := install(t)
+	tests := []struct {
+		name   string
+		state  string
+		cookie *http.Cookie
+	}{
+		{"ohne Cookie", state, nil},
+		{"fremdes Cookie", state, &http.Cookie{Name: stateCookie, Value: "0123456789abcdef"}},
+		{"ohne state", "", cookie},
+		{"unbekannter state", "0123456789abcdef", &http.Cookie{Name: stateCookie, Value: "0123456789abcdef"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := callback(tt.state, tt.cookie); w.Code != 400 {
+				t.Fatalf("Status %d, erwartet 400", w.Code)
+			}
+		})
+	}
+
+	// Abgelehnte Versuche verbrauchen den state nicht
+	if w := callback(state, cookie); w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthStateOnlyOnce(t *testing.T) {
+	forgetInstallation(t)
+	state, cookie := install(t)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- callback(state, cookie).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	succeeded := 0
+	for code := range codes {
+		if code == 200 {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d erfolgreiche Callbacks mit demselben state, erwartet 1", succeeded)
+	}
+}
+
+func TestOAuthAllowedTeams(t *testing.T) {
+	forgetInstallation(t)
+	t.Setenv("SLACK_ALLOWED_TEAMS", "T11111111, T22222222")
+	state, cookie := install(t)
+
+	if w := callback(state, cookie); w.Code != 403 {
+		t.Fatalf("Status %d, erwartet 403", w.Code)
+	}
+	if _, err := slack.Installations.Load(fake.TeamID); err == nil {
+		t.Fatal("Installation eines nicht erlaubten Workspaces gespeichert")
+	}
+
+	t.Setenv("SLACK_ALLOWED_TEAMS", "T11111111,"+fake.TeamID)
+	state, cookie = install(t)
+	if w := callback(state, cookie); w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+}
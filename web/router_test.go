@@ -0,0 +1,231 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-slack-ics/slack"
+	"go-slack-ics/slack/slacktest"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// fake ist der Slack-Fake, gegen den App.Router() in allen Tests des Pakets läuft.
+var fake *slacktest.Server
+
+func TestMain(m *testing.M) {
+	redis, err := miniredis.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fake = slacktest.NewServer()
+
+	os.Setenv("REDIS_ADDR", redis.Addr())
+	os.Setenv("SLACK_API_URL", fake.APIURL())
+	os.Setenv("SLACK_TOKEN", "xoxb-test")
+	os.Setenv("SLACK_SIGNING_SECRET", testSecret)
+	os.Setenv("GPT_MODE", "mention")
+	// apps.connections.open erlaubt nur einen Aufruf pro Minute, die Tests verbinden sich öfter
+	slack.DefaultClient.Limits = map[string]int{"apps.connections.open": 6000}
+	// Templates und Kalender liegen relativ zum Wurzelverzeichnis des Repos
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	fake.Close()
+	redis.Close()
+	os.Exit(code)
+}
+
+var testRouter *gin.Engine
+
+func router() *gin.Engine {
+	if testRouter == nil {
+		testRouter = App{}.Router()
+	}
+	return testRouter
+}
+
+// post schickt eine signierte Anfrage an den Router.
+func post(t *testing.T, path string, contentType string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	sign(r, testSecret, body, time.Now())
+	w := httptest.NewRecorder()
+	router().ServeHTTP(w, r)
+	return w
+}
+
+// eventID liefert eine neue event_id. Events mit bekannter ID verwirft der Router als
+// Wiederholung, auch bei go test -count.
+func eventID() string {
+	return fmt.Sprintf("Ev%d", time.Now().UnixNano())
+}
+
+// waitCalls wartet, bis der Fake n Aufrufe von method gesehen hat. Events und Interaktionen
+// laufen nach der Bestätigung im Hintergrund weiter.
+func waitCalls(method string, n int) []slacktest.Call {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		calls := fake.Calls(method)
+		if len(calls) >= n || time.Now().After(deadline) {
+			return calls
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRouterURLVerification(t *testing.T) {
+	w := post(t, "/slack/events", "application/json", `{"type":"url_verification","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`)
+	if w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+	var response struct{ Challenge string }
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Challenge != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+		t.Fatalf("challenge = %q", response.Challenge)
+	}
+}
+
+func TestRouterRejectsUnsigned(t *testing.T) {
+	for _, path := range []string{"/slack/events", "/slack/commands", "/slack/interactive", "/abfuhr"} {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"type":"url_verification","challenge":"x"}`))
+		w := httptest.NewRecorder()
+		router().ServeHTTP(w, r)
+		if w.Code != 401 {
+			t.Errorf("%s ohne Signatur: Status %d", path, w.Code)
+		}
+	}
+}
+
+func TestRouterSignedEvent(t *testing.T) {
+	fake.Reset()
+	event := `{"type":"event_callback","team_id":"T00000000","event_id":"` + eventID() + `","event":{"type":"app_home_opened","user":"U123","channel":"D123","tab":"home"}}`
+
+	w := post(t, "/slack/events", "application/json", event)
+	if w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+	calls := waitCalls("views.publish", 1)
+	if len(calls) != 1 || calls[0].Value("user_id") != "U123" {
+		t.Fatalf("views.publish: %+v", calls)
+	}
+
+	// Slack wiederholt Events mit derselben event_id, sie dürfen nur einmal wirken
+	if w := post(t, "/slack/events", "application/json", event); w.Code != 200 {
+		t.Fatalf("Wiederholung: Status %d", w.Code)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := len(fake.Calls("views.publish")); n != 1 {
+		t.Fatalf("%d Aufrufe von views.publish nach der Wiederholung, erwartet 1", n)
+	}
+}
+
+func TestRouterSlashCommands(t *testing.T) {
+	tests := []struct {
+		name, path, command, text string
+		contains                  string
+		responseType              string
+	}{
+		{"hilfe", "/slack/commands", "/abfuhr", "help", "/abfuhr stats", "ephemeral"},
+		{"alte Route", "/abfuhr", "/abfuhr", "hilfe", "/abfuhr erledigt", "ephemeral"},
+		{"unbekannter Befehl", "/slack/commands", "/gibtsnicht", "", "/gibtsnicht", "ephemeral"},
+		{"ungültiges Jahr", "/slack/commands", "/abfuhr", "stats 1800", "1800", "ephemeral"},
+		{"Bild ohne Beschreibung", "/clipdrop/tti", "/tti", "", "`/tti <Beschreibung>`", "ephemeral"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{
+				"command":    {tt.command},
+				"text":       {tt.text},
+				"user_id":    {"U123"},
+				"channel_id": {"C123"},
+				"team_id":    {"T00000000"},
+			}
+			w := post(t, tt.path, "application/x-www-form-urlencoded", form.Encode())
+			if w.Code != 200 {
+				t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Text         string `json:"text"`
+				ResponseType string `json:"response_type"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(response.Text, tt.contains) {
+				t.Errorf("Antwort %q enthält %q nicht", response.Text, tt.contains)
+			}
+			if response.ResponseType != tt.responseType {
+				t.Errorf("response_type = %q", response.ResponseType)
+			}
+		})
+	}
+}
+
+func TestRouterInteraction(t *testing.T) {
+	fake.Reset()
+	payload := map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"id": "U123"},
+		"team":         map[string]string{"id": "T00000000"},
+		"response_url": fake.ResponseURL(),
+		"actions": []map[string]string{
+			{"action_id": "calendar_ack", "block_id": "calendar_ack", "value": "gibt-es-nicht@awbkoeln.de", "type": "button"},
+		},
+	}
+	data, _ := json.Marshal(payload)
+
+	w := post(t, "/slack/interactive", "application/x-www-form-urlencoded", url.Values{"payload": {string(data)}}.Encode())
+	if w.Code != 200 {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+
+	calls := waitCalls("response_url", 1)
+	if len(calls) != 1 {
+		t.Fatalf("%d Antworten an die response_url, erwartet 1", len(calls))
+	}
+	if calls[0].Value("response_type") != "ephemeral" || calls[0].Value("text") == "" {
+		t.Fatalf("Antwort: %s", calls[0].Body)
+	}
+}
+
+func TestRouterInteractionInvalidPayload(t *testing.T) {
+	w := post(t, "/slack/interactive", "application/x-www-form-urlencoded", url.Values{"payload": {"{kaputt"}}.Encode())
+	if w.Code != 400 {
+		t.Fatalf("Status %d, erwartet 400", w.Code)
+	}
+}
+
+func TestRouterConversationsNeedAdmin(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "geheim")
+	for _, tt := range []struct {
+		header string
+		status int
+	}{
+		{"", 401},
+		{"Bearer falsch", 401},
+		{"Bearer geheim", 200},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/gpt-conversations", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router().ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("Authorization %q: Status %d, erwartet %d", tt.header, w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Slack verwirft Anfragen, die älter als fünf Minuten sind; wir ebenso, gegen Replays.
+const signatureWindow = 5 * time.Minute
+
+// SlackSignature prüft X-Slack-Signature und X-Slack-Request-Timestamp mit dem Signing Secret
+// der App. Der Body wird danach wiederhergestellt, damit die Handler ihn normal lesen können.
+// Anfragen aus Socket Mode sind bereits über die Verbindung authentifiziert.
+func SlackSignature(secret string) gin.HandlerFunc {
+	if secret == "" {
+		log.Printf("SLACK_SIGNING_SECRET ist nicht gesetzt, Slack-Routen lehnen alle Anfragen über HTTP ab")
+	}
+
+	return func(c *gin.Context) {
+		if fromSocketMode(c.Request.Context()) {
+			c.Next()
+			return
+		}
+		if secret == "" {
+			c.AbortWithStatusJSON(500, gin.H{"error": "signing secret not configured"})
+			return
+		}
+
+		timestamp := c.GetHeader("X-Slack-Request-Timestamp")
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"error": "invalid timestamp"})
+			return
+		}
+		if age := time.Since(time.Unix(ts, 0)); age > signatureWindow || age < -signatureWindow {
+			c.AbortWithStatusJSON(401, gin.H{"error": "request too old"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !validSignature(secret, timestamp, body, c.GetHeader("X-Slack-Signature")) {
+			c.AbortWithStatusJSON(401, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func validSignature(secret string, timestamp string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
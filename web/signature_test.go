@@ -0,0 +1,115 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// Beispiel aus der Slack-Dokumentation "Verifying requests from Slack".
+const (
+	recordedTimestamp = "1531420618"
+	recordedBody      = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	recordedSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// sign setzt die Header, die Slack für body zum Zeitpunkt at schicken würde.
+func sign(r *http.Request, secret string, body string, at time.Time) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestValidSignatureRecorded(t *testing.T) {
+	if !validSignature(testSecret, recordedTimestamp, []byte(recordedBody), recordedSignature) {
+		t.Fatal("aufgezeichnete Signatur wird abgelehnt")
+	}
+	if validSignature(testSecret, recordedTimestamp, []byte(recordedBody+"&x=1"), recordedSignature) {
+		t.Fatal("veränderter Body wird akzeptiert")
+	}
+}
+
+func TestSlackSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(r *http.Request)
+		ctx     context.Context
+		status  int
+	}{
+		{"gültig", func(r *http.Request) { sign(r, testSecret, recordedBody, time.Now()) }, nil, 200},
+		{"falsche Signatur", func(r *http.Request) { sign(r, "anderes-secret", recordedBody, time.Now()) }, nil, 401},
+		{"veraltet", func(r *http.Request) { sign(r, testSecret, recordedBody, time.Now().Add(-6*time.Minute)) }, nil, 401},
+		{"aus der Zukunft", func(r *http.Request) { sign(r, testSecret, recordedBody, time.Now().Add(6*time.Minute)) }, nil, 401},
+		{"aufgezeichneter Zeitstempel", func(r *http.Request) {
+			r.Header.Set("X-Slack-Request-Timestamp", recordedTimestamp)
+			r.Header.Set("X-Slack-Signature", recordedSignature)
+		}, nil, 401},
+		{"ohne Header", func(r *http.Request) {}, nil, 401},
+		{"ohne Signatur", func(r *http.Request) {
+			sign(r, testSecret, recordedBody, time.Now())
+			r.Header.Del("X-Slack-Signature")
+		}, nil, 401},
+		{"Socket Mode", func(r *http.Request) {}, context.WithValue(context.Background(), socketModeKey{}, true), 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.POST("/slack", SlackSignature(testSecret), func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				seen = string(body)
+				c.Status(200)
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(recordedBody))
+			if tt.ctx != nil {
+				r = r.WithContext(tt.ctx)
+			}
+			tt.prepare(r)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("Status %d, erwartet %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == 200 && seen != recordedBody {
+				t.Fatalf("Handler liest %q, erwartet den ursprünglichen Body", seen)
+			}
+			if tt.status != 200 && seen != "" {
+				t.Fatal("Handler wurde trotz Ablehnung aufgerufen")
+			}
+		})
+	}
+}
+
+func TestSlackSignatureWithoutSecret(t *testing.T) {
+	router := gin.New()
+	router.POST("/slack", SlackSignature(""), func(c *gin.Context) { c.Status(200) })
+
+	r := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(recordedBody))
+	sign(r, "", recordedBody, time.Now())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 500 {
+		t.Fatalf("Status %d, ohne Secret muss jede Anfrage abgelehnt werden", w.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-slack-ics/slack/socketmode"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// socketModeKey markiert Anfragen, die aus einer Socket-Mode-Verbindung stammen. Sie kommen
+// nicht über das Netz und brauchen keine Signatur, Slack hat die Verbindung authentifiziert.
+type socketModeKey struct{}
+
+func fromSocketMode(ctx context.Context) bool {
+	return ctx.Value(socketModeKey{}) != nil
+}
+
+// SocketMode hält die Socket-Mode-Verbindung mit dem App-Level-Token aus SLACK_APP_TOKEN, bis
+// ctx beendet ist. Die Envelopes laufen durch den übergebenen Router, also durch dieselben
+// Handler wie die HTTP-Anfragen von Slack.
+func SocketMode(ctx context.Context, appToken string, router http.Handler) error {
+	client := socketmode.Client{
+		AppToken: appToken,
+		Handler:  routeEnvelope(router),
+	}
+	return client.Run(ctx)
+}
+
+// routeEnvelope baut aus einem Envelope die Anfrage, die Slack per HTTP geschickt hätte, und
+// liefert die Antwort des Routers für das Ack.
+func routeEnvelope(router http.Handler) socketmode.Handler {
+	return func(ctx context.Context, envelope socketmode.Envelope) (json.RawMessage, error) {
+		var path, contentType string
+		var body []byte
+
+		switch envelope.Type {
+		case socketmode.TypeEventsAPI:
+			path, contentType, body = "/slack/events", "application/json", envelope.Payload
+		case socketmode.TypeInteractive:
+			form := url.Values{"payload": {string(envelope.Payload)}}
+			path, contentType, body = "/slack/interactive", "application/x-www-form-urlencoded", []byte(form.Encode())
+		case socketmode.TypeSlashCommand:
+			var fields map[string]interface{}
+			if err := json.Unmarshal(envelope.Payload, &fields); err != nil {
+				return nil, err
+			}
+			form := url.Values{}
+			for key, value := range fields {
+				form.Set(key, fmt.Sprint(value))
+			}
+			path, contentType, body = "/slack/commands", "application/x-www-form-urlencoded", []byte(form.Encode())
+		default:
+			return nil, fmt.Errorf("unbekannter Envelope-Typ %s", envelope.Type)
+		}
+
+		request, err := http.NewRequestWithContext(context.WithValue(ctx, socketModeKey{}, true), http.MethodPost, path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", contentType)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			return nil, fmt.Errorf("%s antwortet mit %d: %s", path, recorder.Code, strings.TrimSpace(recorder.Body.String()))
+		}
+		return recorder.Body.Bytes(), nil
+	}
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"go-slack-ics/slack/socketmode"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envelope(envelopeType string, payload string) socketmode.Envelope {
+	return socketmode.Envelope{EnvelopeID: "E1", Type: envelopeType, Payload: json.RawMessage(payload), AcceptsResponsePayload: true}
+}
+
+func TestRouteEnvelope(t *testing.T) {
+	interaction, _ := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"id": "U123"},
+		"team":         map[string]string{"id": "T00000000"},
+		"response_url": fake.ResponseURL(),
+		"actions": []map[string]string{
+			{"action_id": "calendar_ack", "block_id": "calendar_ack", "value": "gibt-es-nicht@awbkoeln.de", "type": "button"},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		envelope socketmode.Envelope
+		contains string
+		method   string
+	}{
+		{"url_verification", envelope(socketmode.TypeEventsAPI, `{"type":"url_verification","challenge":"abc123"}`), `"challenge":"abc123"`, ""},
+		{"event", envelope(socketmode.TypeEventsAPI, `{"type":"event_callback","team_id":"T00000000","event_id":"`+eventID()+`","event":{"type":"app_home_opened","user":"U456","channel":"D456","tab":"home"}}`), "", "views.publish"},
+		{"slash command", envelope(socketmode.TypeSlashCommand, `{"command":"/abfuhr","text":"help","user_id":"U123","channel_id":"C123","team_id":"T00000000"}`), "/abfuhr stats", ""},
+		{"interaktion", envelope(socketmode.TypeInteractive, string(interaction)), "", "response_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Reset()
+			payload, err := routeEnvelope(router())(context.Background(), tt.envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(payload), tt.contains) {
+				t.Errorf("Antwort %s enthält %s nicht", payload, tt.contains)
+			}
+			if tt.method != "" {
+				if calls := waitCalls(tt.method, 1); len(calls) != 1 {
+					t.Errorf("%d Aufrufe von %s, erwartet 1", len(calls), tt.method)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteEnvelopeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope socketmode.Envelope
+	}{
+		{"unbekannter Typ", envelope("hello_world", `{}`)},
+		{"kaputter Befehl", envelope(socketmode.TypeSlashCommand, `{kaputt`)},
+		{"kaputte Interaktion", envelope(socketmode.TypeInteractive, `{kaputt`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := routeEnvelope(router())(context.Background(), tt.envelope); err == nil {
+				t.Fatal("kein Fehler")
+			}
+		})
+	}
+}
+
+// TestSocketMode schickt einen Slash-Befehl über die WebSocket-Verbindung des Fakes, die Antwort
+// kommt mit dem Ack zurück.
+func TestSocketMode(t *testing.T) {
+	connections := fake.Connections()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- SocketMode(ctx, "xapp-test", router()) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if !fake.WaitConnected(connections+1, 2*time.Second) {
+		t.Fatal("keine Socket-Mode-Verbindung")
+	}
+	id, err := fake.SendEnvelope(socketmode.TypeSlashCommand, map[string]string{
+		"command": "/abfuhr", "text": "help", "user_id": "U123", "channel_id": "C123", "team_id": "T00000000",
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, ok := fake.WaitAck(id, 2*time.Second)
+	if !ok {
+		t.Fatal("kein Ack")
+	}
+	var response struct {
+		Text         string `json:"text"`
+		ResponseType string `json:"response_type"`
+	}
+	if err := json.Unmarshal(payload, &response); err != nil {
+		t.Fatalf("%v: %s", err, payload)
+	}
+	if !strings.Contains(response.Text, "/abfuhr stats") || response.ResponseType != "ephemeral" {
+		t.Fatalf("Ack-Payload %s", payload)
+	}
+}
@@ -1,133 +1,180 @@
 package web
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
-	"go-slack-ics/clipdrop"
+	"go-slack-ics/calendar"
 	"go-slack-ics/gpt"
-	"go-slack-ics/leonardo"
+	"go-slack-ics/home"
+	"go-slack-ics/i18n"
 	"go-slack-ics/mock"
 	"go-slack-ics/mock/graphql"
 	"go-slack-ics/slack"
 	"go-slack-ics/system"
-	"io"
 	"log"
-	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
 type App struct{}
 
-func (App) ServeHTTP() {
+// imageTimeout begrenzt Bildgenerierung und Upload, die nach der Antwort an Slack weiterlaufen.
+const imageTimeout = 5 * time.Minute
+
+// reportError protokolliert den Fehler und meldet ihn im Kanal, statt den Server zu beenden.
+func reportError(ctx context.Context, channel string, err error) {
+	log.Printf("Fehler in %s: %v", channel, err)
+	message := slack.GetSimpleMessage("", channel, i18n.T(i18n.Default(), "error.generic", err.Error()))
+	if _, err := slack.Instance.SendMessage(ctx, channel, "", message); err != nil {
+		log.Printf("Fehler konnte nicht an %s gemeldet werden: %v", channel, err)
+	}
+}
+
+// Router baut die gin-Engine mit allen Routen auf, ohne sie zu starten. So lässt sich der
+// Router auch gegen den Fake-Server aus slack/slacktest betreiben.
+func (App) Router() *gin.Engine {
 	r := gin.Default()
 
 	templatePath := "templates/*" // Setze den Template-Pfad
 	r.LoadHTMLGlob(templatePath)
 
 	eventManager := system.NewEventManager()
+
+	// Alle Routen, die Slack aufruft, müssen signiert sein
+	slackRoutes := r.Group("/", SlackSignature(os.Getenv("SLACK_SIGNING_SECRET")))
+	oauthRoutes(r)
 	r.GET("/", func(c *gin.Context) {
-		c.String(200, "Hello World!")
+		c.String(200, i18n.T(i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")), "web.hello"))
 	})
 
 	r.GET("/go", func(c *gin.Context) {
-		c.String(200, "Du bist im Go-Pfad!")
-	})
-
-	r.POST("/gpt-conversations", func(c *gin.Context) {
-		response := gpt.GetConversations()
-		c.JSON(200, response)
+		c.String(200, i18n.T(i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")), "web.go"))
 	})
 
-	r.POST("/text-to-image", func(c *gin.Context) {
-		body, err := io.ReadAll(c.Request.Body)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+	r.POST("/admin/calendar/reload", adminAuth(), func(c *gin.Context) {
+		if err := calendar.DefaultStore.Reload(); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 
-		values, err := url.ParseQuery(string(body))
+		loaded := gin.H{}
+		for _, source := range calendar.Sources {
+			loaded[source.Name] = calendar.DefaultStore.LoadedAt(source.Name)
+		}
+		c.JSON(200, gin.H{"success": true, "loaded": loaded})
+	})
 
-		var event slack.Command
-		event.ChannelID = values.Get("channel_id")
-		event.Text = values.Get("text")
-		event.Command = values.Get("command")
+	r.GET("/admin/calendar/stats", adminAuth(), func(c *gin.Context) {
+		year := time.Now().Year()
+		if c.Query("year") != "" {
+			var err error
+			if year, err = strconv.Atoi(c.Query("year")); err != nil {
+				c.JSON(400, gin.H{"error": "invalid year"})
+				return
+			}
+		}
 
-		if event.Text == "" {
-			c.JSON(200, gin.H{
-				"response_type": "in_channel",
-				"error":         "text is empty",
-			})
+		report, err := calendar.BuildReport(year)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
+		c.JSON(200, report)
+	})
 
-		go func() {
-			bodyBytes, filename, err := leonardo.NewTextToImage(event.Text)
-			if err != nil {
+	// Alle Slash-Befehle können auf /slack/commands zeigen, die alten Routen bleiben für
+	// bestehende App-Konfigurationen erhalten
+	commands := newCommandRouter()
+	commandRoute := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if err := c.Request.ParseForm(); err != nil {
 				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
-
-			err = slack.Instance.SendImageToSlack(bodyBytes, filename, event.Text, event.ChannelID)
-			if err != nil {
-				log.Fatal(err.Error())
+			command := slack.ParseCommand(c.Request.PostForm)
+			as := name
+			if as == "" {
+				as = command.Command
 			}
-		}()
+			c.JSON(200, commands.DispatchAs(c.Request.Context(), as, command))
+		}
+	}
+	slackRoutes.POST("/slack/commands", commandRoute(""))
+	slackRoutes.POST("/abfuhr", commandRoute(calendar.CommandName))
+	slackRoutes.POST("/text-to-image", commandRoute(commandTextToImage))
+	slackRoutes.POST("/clipdrop/tti", commandRoute(commandClipdrop))
 
-		c.JSON(200, gin.H{
-			"response_type": "in_channel",
-		})
+	// Liefert alle gespeicherten Verläufe aller Workspaces und Kanäle
+	r.POST("/gpt-conversations", adminAuth(), func(c *gin.Context) {
+		response := gpt.GetConversations()
+		c.JSON(200, response)
 	})
 
-	r.POST("/clipdrop/tti", func(c *gin.Context) {
-		tti := clipdrop.NewTextToImage()
-		var event slack.Command
-		body, err := io.ReadAll(c.Request.Body)
+	events := slack.NewEventDispatcher(func(eventID string) bool {
+		// Die ID wird eine Stunde gemerkt, Slack versucht es höchstens dreimal innerhalb weniger Minuten
+		isNew, err := system.RedisInstance().SetNX("slack:event:"+eventID, 1, time.Hour)
 		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
+			log.Printf("Fehler beim Prüfen der event_id %s: %v", eventID, err)
+			return false
 		}
-
-		values, err := url.ParseQuery(string(body))
-
-		event.ChannelID = values.Get("channel_id")
-		event.Text = values.Get("text")
-		event.Command = values.Get("command")
-
-		if event.Text == "" {
-			c.JSON(200, gin.H{
-				"response_type": "in_channel",
-				"error":         "text is empty",
-			})
+		return !isNew
+	})
+	// Erwähnungen kommen ebenfalls als message-Event, app_mention würde doppelt antworten
+	gptFilter := gpt.NewFilter()
+	events.On("message", func(payload slack.Payload) {
+		if !gptFilter.ForPayload(payload).Accept(payload.Event) {
 			return
 		}
+		chat := gpt.NewChat(eventManager)
+		chat.SendAsync(slack.WithTeam(context.Background(), payload.TeamID), payload.Event)
+	})
 
-		go func() {
-			_, err := tti.Prompt(event)
-			if err != nil {
-				log.Fatal(err.Error())
-			}
-		}()
+	events.On("app_uninstalled", slack.HandleUninstall)
+	events.On("tokens_revoked", slack.HandleUninstall)
 
-		c.JSON(200, gin.H{
-			"response_type": "in_channel",
-		})
-	})
+	interactions := slack.NewInteractionDispatcher()
+	calendar.RegisterInteractions(interactions)
+	home.Register(events, interactions)
 
-	r.POST("/gpt-event", func(c *gin.Context) {
-		chat := gpt.NewChat(eventManager)
+	eventsRoute := func(c *gin.Context) {
 		var payload slack.Payload
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		chat.SendAsync(payload.Event)
+		if payload.Type == "url_verification" {
+			c.JSON(200, gin.H{"challenge": payload.Challenge})
+			return
+		}
+
+		events.Dispatch(payload)
 		response := slack.Response{
 			Ok: true,
 		}
 		c.JSON(200, response)
+	}
+	slackRoutes.POST("/slack/events", eventsRoute)
+
+	slackRoutes.POST("/slack/interactive", func(c *gin.Context) {
+		var payload slack.InteractionPayload
+		if err := json.Unmarshal([]byte(c.PostForm("payload")), &payload); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if response := interactions.Dispatch(c.Request.Context(), payload); response != nil {
+			c.JSON(200, response)
+			return
+		}
+		c.Status(200)
 	})
+	slackRoutes.POST("/gpt-event", eventsRoute)
 
-	r.POST("/gpt-cancel", func(c *gin.Context) {
+	slackRoutes.POST("/gpt-cancel", func(c *gin.Context) {
 		var event slack.Event
 		if err := c.ShouldBindJSON(&event); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
@@ -148,7 +195,7 @@ func (App) ServeHTTP() {
 		c.JSON(200, gin.H{"success": eventManager.HasChannel(event.Channel)})
 	})
 
-	r.POST("/gpt", func(c *gin.Context) {
+	slackRoutes.POST("/gpt", func(c *gin.Context) {
 		chat := gpt.NewChat(eventManager)
 		var event slack.Event
 		if err := c.ShouldBindJSON(&event); err != nil {
@@ -156,7 +203,9 @@ func (App) ServeHTTP() {
 			return
 		}
 
-		chat.SendAsync(event)
+		if gptFilter.Accept(event) {
+			chat.SendAsync(slack.WithTeam(context.Background(), event.TeamID), event)
+		}
 		response := slack.Response{
 			Ok: true,
 		}
@@ -172,9 +221,25 @@ func (App) ServeHTTP() {
 	r.POST("/admin/api/graphql.json", shopifyGraphql.GraphQLHandler)
 
 	r.NoRoute(func(c *gin.Context) {
-		c.String(404, "Nicht gefunden")
+		c.String(404, i18n.T(i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")), "web.not_found"))
 	})
 
+	return r
+}
+
+func (app App) ServeHTTP() {
+	r := app.Router()
+
+	// Mit einem App-Level-Token kommen Events, Befehle und Interaktionen über Socket Mode,
+	// dafür braucht der Bot keine öffentliche HTTPS-Adresse
+	if appToken := os.Getenv("SLACK_APP_TOKEN"); appToken != "" {
+		go func() {
+			if err := SocketMode(context.Background(), appToken, r); err != nil {
+				log.Printf("Socket Mode beendet: %v", err)
+			}
+		}()
+	}
+
 	// Lade die SSL-Zertifikate
 	var err error
 	if os.Getenv("GIN_SSL") == "true" {
@@ -188,6 +253,18 @@ func (App) ServeHTTP() {
 	}
 }
 
+// adminAuth schützt die Admin-Routen mit dem Bearer-Token aus ADMIN_TOKEN.
+func adminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatusJSON(401, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func Start() {
 	app := App{}
 	app.ServeHTTP()